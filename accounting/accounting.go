@@ -0,0 +1,399 @@
+// Package accounting computes the cost basis and the realized gains of an
+// account from its executed orders and transfers.
+//
+// Every acquisition of a currency opens a lot valued in the reference
+// currency of the ledger, fees included. Disposals are matched against the
+// open lots FIFO, LIFO or at average cost, and the gain of each match is
+// reported by lot and by year. Transfers move funds in and out of the
+// account without realizing gains.
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// Method is the lot matching method of a ledger.
+type Method int
+
+// Lot matching methods.
+const (
+	// FIFO disposes of the oldest lots first.
+	FIFO Method = iota
+	// LIFO disposes of the newest lots first.
+	LIFO
+	// AverageCost pools the lots of a currency at their average cost.
+	AverageCost
+)
+
+// String returns the name of the method.
+func (m Method) String() string {
+	switch m {
+	case FIFO:
+		return "fifo"
+	case LIFO:
+		return "lifo"
+	case AverageCost:
+		return "average"
+	default:
+		return "unknown"
+	}
+}
+
+// epsilon is the amount under which a lot is considered empty.
+const epsilon = 1e-12
+
+// Config represents the configuration of a ledger.
+type Config struct {
+	// Currency is the reference currency, for example CLP.
+	Currency string
+	Method   Method
+	// Location is the time zone of the dates of the API and of the years
+	// of the report. UTC when nil.
+	Location *time.Location
+	// Price returns the price of one unit of currency in the reference
+	// currency at t. It is required for fills in markets not quoted in the
+	// reference currency, and for deposits without cost.
+	Price func(currency string, t time.Time) (float64, error)
+}
+
+// Fill represents an execution of an order.
+type Fill struct {
+	OrderID string
+	Time    time.Time
+	Market  string
+	// Side is buy or sell.
+	Side string
+	// Amount is the executed amount of base currency.
+	Amount float64
+	// Price is the price in quote currency.
+	Price float64
+	// Fee is the fee paid in quote currency.
+	Fee float64
+}
+
+// Transfer represents funds entering or leaving the account.
+type Transfer struct {
+	ID       string
+	Time     time.Time
+	Currency string
+	// Amount is positive for deposits and negative for withdrawals.
+	Amount float64
+	// Fee is the fee paid in Currency, on top of Amount.
+	Fee float64
+	// Cost is the cost basis of a deposit in the reference currency. When
+	// zero the deposit is valued with Config.Price.
+	Cost float64
+}
+
+// Lot represents an acquisition of a currency.
+type Lot struct {
+	ID       string
+	Currency string
+	Acquired time.Time
+	// Amount is the amount still held.
+	Amount float64
+	// Cost is the cost of Amount in the reference currency.
+	Cost float64
+}
+
+// Disposal represents the part of a lot matched by a disposal.
+type Disposal struct {
+	LotID    string
+	OrderID  string
+	Currency string
+	Acquired time.Time
+	Disposed time.Time
+	Amount   float64
+	// Cost, Proceeds and Gain are in the reference currency. Proceeds are
+	// net of fees.
+	Cost     float64
+	Proceeds float64
+	Gain     float64
+}
+
+// YearReport represents the realized gains of a year.
+type YearReport struct {
+	Year      int
+	Disposals int
+	Proceeds  float64
+	Cost      float64
+	Gain      float64
+	// Fees are the fees paid in the year, in the reference currency.
+	Fees float64
+}
+
+// Report represents the results of a ledger.
+type Report struct {
+	Currency  string
+	Method    Method
+	Disposals []*Disposal
+	// Years are sorted by year.
+	Years []*YearReport
+	// OpenLots are the lots still held, by currency and acquisition.
+	OpenLots []*Lot
+}
+
+// event is a fill or a transfer.
+type event struct {
+	at       time.Time
+	fill     *Fill
+	transfer *Transfer
+}
+
+// Ledger matches the disposals of an account against its lots.
+type Ledger struct {
+	cfg    Config
+	events []*event
+
+	lots   map[string][]*Lot
+	lastID int
+	report *Report
+	years  map[int]*YearReport
+}
+
+// New returns a ledger configured with cfg.
+func New(cfg Config) *Ledger {
+	cfg.Currency = strings.ToUpper(cfg.Currency)
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	return &Ledger{cfg: cfg}
+}
+
+// AddFills records fills, in any order.
+func (l *Ledger) AddFills(fills ...*Fill) {
+	for _, f := range fills {
+		l.events = append(l.events, &event{at: f.Time, fill: f})
+	}
+}
+
+// AddTransfers records transfers, in any order.
+func (l *Ledger) AddTransfers(transfers ...*Transfer) {
+	for _, t := range transfers {
+		l.events = append(l.events, &event{at: t.Time, transfer: t})
+	}
+}
+
+// Run processes the recorded fills and transfers in chronological order
+// and returns the report. Disposing of more than is held is an error: the
+// history is incomplete.
+func (l *Ledger) Run() (*Report, error) {
+	if l.cfg.Currency == "" {
+		return nil, errors.New("accounting: reference currency is required")
+	}
+
+	events := append([]*event(nil), l.events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	l.lots = make(map[string][]*Lot)
+	l.lastID = 0
+	l.report = &Report{Currency: l.cfg.Currency, Method: l.cfg.Method}
+	l.years = make(map[int]*YearReport)
+	for _, ev := range events {
+		var err error
+		if ev.fill != nil {
+			err = l.fill(ev.fill)
+		} else {
+			err = l.transfer(ev.transfer)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, y := range l.years {
+		l.report.Years = append(l.report.Years, y)
+	}
+	sort.Slice(l.report.Years, func(i, j int) bool { return l.report.Years[i].Year < l.report.Years[j].Year })
+
+	currencies := make([]string, 0, len(l.lots))
+	for c := range l.lots {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+	for _, c := range currencies {
+		l.report.OpenLots = append(l.report.OpenLots, l.lots[c]...)
+	}
+	return l.report, nil
+}
+
+// fill applies f. Both sides of a market not quoted in the reference
+// currency are acquisitions or disposals.
+func (l *Ledger) fill(f *Fill) error {
+	base, quote, err := cryptomkt.SplitMarket(f.Market)
+	if err != nil {
+		return err
+	}
+	if f.Side != "buy" && f.Side != "sell" {
+		return fmt.Errorf("accounting: invalid side %q of order %s", f.Side, f.OrderID)
+	}
+	rate, err := l.price(quote, f.Time)
+	if err != nil {
+		return err
+	}
+	notional := f.Amount * f.Price * rate
+	fee := f.Fee * rate
+	l.year(f.Time).Fees += fee
+
+	if f.Side == "buy" {
+		if quote != l.cfg.Currency {
+			// The quote paid is disposed of at its value.
+			if err := l.dispose(quote, f.Amount*f.Price+f.Fee, notional+fee, f.OrderID, f.Time); err != nil {
+				return err
+			}
+		}
+		l.acquire(base, f.Amount, notional+fee, f.Time)
+		return nil
+	}
+
+	if err := l.dispose(base, f.Amount, notional-fee, f.OrderID, f.Time); err != nil {
+		return err
+	}
+	if quote != l.cfg.Currency {
+		l.acquire(quote, f.Amount*f.Price-f.Fee, notional-fee, f.Time)
+	}
+	return nil
+}
+
+// transfer applies t. Deposits open a lot, withdrawals close lots without
+// realizing gains.
+func (l *Ledger) transfer(t *Transfer) error {
+	currency := strings.ToUpper(t.Currency)
+	if currency == l.cfg.Currency {
+		return nil
+	}
+	if t.Amount >= 0 {
+		cost := t.Cost
+		if cost == 0 {
+			price, err := l.price(currency, t.Time)
+			if err != nil {
+				return err
+			}
+			cost = t.Amount * price
+		}
+		l.acquire(currency, t.Amount, cost, t.Time)
+		return nil
+	}
+
+	_, err := l.take(currency, -t.Amount+t.Fee, t.ID)
+	return err
+}
+
+// price returns the price of currency in the reference currency at t.
+func (l *Ledger) price(currency string, t time.Time) (float64, error) {
+	if currency == l.cfg.Currency {
+		return 1, nil
+	}
+	if l.cfg.Price == nil {
+		return 0, fmt.Errorf("accounting: no price of %s in %s", currency, l.cfg.Currency)
+	}
+	return l.cfg.Price(currency, t)
+}
+
+// acquire opens a lot.
+func (l *Ledger) acquire(currency string, amount, cost float64, at time.Time) {
+	if currency == l.cfg.Currency || amount <= 0 {
+		return
+	}
+	if l.cfg.Method == AverageCost && len(l.lots[currency]) > 0 {
+		pool := l.lots[currency][0]
+		pool.Amount += amount
+		pool.Cost += cost
+		return
+	}
+	l.lastID++
+	lot := &Lot{
+		ID:       fmt.Sprintf("L%d", l.lastID),
+		Currency: currency,
+		Acquired: at,
+		Amount:   amount,
+		Cost:     cost,
+	}
+	l.lots[currency] = append(l.lots[currency], lot)
+}
+
+// dispose closes amount of currency for proceeds and records the gains.
+func (l *Ledger) dispose(currency string, amount, proceeds float64, orderID string, at time.Time) error {
+	if currency == l.cfg.Currency {
+		return nil
+	}
+	matches, err := l.take(currency, amount, orderID)
+	if err != nil {
+		return err
+	}
+	y := l.year(at)
+	for _, d := range matches {
+		d.OrderID = orderID
+		d.Disposed = at
+		d.Proceeds = proceeds * d.Amount / amount
+		d.Gain = d.Proceeds - d.Cost
+		l.report.Disposals = append(l.report.Disposals, d)
+
+		y.Disposals++
+		y.Proceeds += d.Proceeds
+		y.Cost += d.Cost
+		y.Gain += d.Gain
+	}
+	return nil
+}
+
+// take removes amount of currency from the lots in the order of the method
+// and returns the matched parts.
+func (l *Ledger) take(currency string, amount float64, ref string) ([]*Disposal, error) {
+	lots := l.lots[currency]
+	held := 0.0
+	for _, lot := range lots {
+		held += lot.Amount
+	}
+	if amount > held+epsilon {
+		return nil, fmt.Errorf("accounting: %s disposes of %v %s with %v held", ref, amount, currency, held)
+	}
+
+	var matches []*Disposal
+	for amount > epsilon && len(lots) > 0 {
+		i := 0
+		if l.cfg.Method == LIFO {
+			i = len(lots) - 1
+		}
+		lot := lots[i]
+		qty := math.Min(amount, lot.Amount)
+		cost := lot.Cost * qty / lot.Amount
+		matches = append(matches, &Disposal{
+			LotID:    lot.ID,
+			Currency: currency,
+			Acquired: lot.Acquired,
+			Amount:   qty,
+			Cost:     cost,
+		})
+		lot.Amount -= qty
+		lot.Cost -= cost
+		amount -= qty
+		if lot.Amount <= epsilon {
+			lots = append(lots[:i], lots[i+1:]...)
+		}
+	}
+	if len(lots) == 0 {
+		delete(l.lots, currency)
+	} else {
+		l.lots[currency] = lots
+	}
+	return matches, nil
+}
+
+// year returns the report of the year of t.
+func (l *Ledger) year(t time.Time) *YearReport {
+	year := t.In(l.cfg.Location).Year()
+	y, ok := l.years[year]
+	if !ok {
+		y = &YearReport{Year: year}
+		l.years[year] = y
+	}
+	return y
+}
@@ -0,0 +1,165 @@
+package accounting
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+	"github.com/Finciero/go-cryptomkt/cryptomkttest"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func newLedger(m Method) *Ledger {
+	l := New(Config{Currency: "clp", Method: m})
+	l.AddFills(
+		&Fill{OrderID: "M3", Time: date("2023-03-01"), Market: "ETHCLP", Side: "sell", Amount: 1.5, Price: 3000000, Fee: 45000},
+		&Fill{OrderID: "M1", Time: date("2022-01-10"), Market: "ETHCLP", Side: "buy", Amount: 1, Price: 1000000, Fee: 10000},
+		&Fill{OrderID: "M2", Time: date("2022-06-01"), Market: "ETHCLP", Side: "buy", Amount: 1, Price: 2000000},
+	)
+	l.AddTransfers(&Transfer{ID: "T1", Time: date("2023-04-01"), Currency: "ETH", Amount: -0.1, Fee: 0.01})
+	return l
+}
+
+func Test_Methods(t *testing.T) {
+	cases := []struct {
+		method   Method
+		gain     float64
+		openCost float64
+	}{
+		{FIFO, 2445000, 780000},
+		{LIFO, 1950000, 393900},
+		{AverageCost, 2197500, 586950},
+	}
+	for _, c := range cases {
+		r, err := newLedger(c.method).Run()
+		if err != nil {
+			t.Errorf("%v: Unexpected error: %v", c.method, err)
+			continue
+		}
+		if len(r.Years) != 2 || r.Years[0].Year != 2022 || r.Years[1].Year != 2023 {
+			t.Errorf("%v: Expected years 2022 and 2023, got %+v", c.method, r.Years)
+			continue
+		}
+		y := r.Years[1]
+		if math.Abs(y.Gain-c.gain) > 1e-6 || math.Abs(y.Proceeds-4455000) > 1e-6 || y.Fees != 45000 || r.Years[0].Fees != 10000 {
+			t.Errorf("%v: Expected gain %v, got %+v", c.method, c.gain, y)
+		}
+		if len(r.OpenLots) != 1 || math.Abs(r.OpenLots[0].Amount-0.39) > 1e-9 || math.Abs(r.OpenLots[0].Cost-c.openCost) > 1e-6 {
+			t.Errorf("%v: Expected 0.39 ETH costing %v, got %+v", c.method, c.openCost, r.OpenLots[0])
+		}
+	}
+}
+
+func Test_FIFODisposals(t *testing.T) {
+	r, err := newLedger(FIFO).Run()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(r.Disposals) != 2 {
+		t.Errorf("Expected 2 disposals, got %d", len(r.Disposals))
+		return
+	}
+	d := r.Disposals[0]
+	if d.LotID != "L1" || d.OrderID != "M3" || d.Amount != 1 || d.Cost != 1010000 || d.Proceeds != 2970000 || d.Gain != 1960000 {
+		t.Errorf("Unexpected first disposal %+v", d)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteDisposalsCSV(&buf); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[1] != "L1,M3,ETH,2022-01-10 00:00:00,2023-03-01 00:00:00,1,1010000,2970000,1960000" {
+		t.Errorf("Unexpected CSV %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.WriteYearsCSV(&buf); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2023,CLP,fifo,2,4455000,2010000,2445000,45000") {
+		t.Errorf("Unexpected CSV %q", buf.String())
+	}
+}
+
+func Test_IncompleteHistory(t *testing.T) {
+	l := New(Config{Currency: "CLP"})
+	l.AddFills(&Fill{OrderID: "M1", Time: date("2023-01-01"), Market: "ETHCLP", Side: "sell", Amount: 1, Price: 1000000})
+	if _, err := l.Run(); err == nil {
+		t.Errorf("Expected error disposing of more than held")
+	}
+}
+
+func Test_Sources(t *testing.T) {
+	pages := map[int]*cryptomkt.MarketOrdersResponse{
+		0: {
+			Data: []*cryptomkt.MarketOrder{{
+				ID: "M1", Type: "buy", Market: "ethclp", AvgExecutionPrice: 1000000,
+				Amount: &cryptomkt.OrderAmount{Original: 1, Executed: 1}, ExecutedAt: "2022-01-10T12:00:00.000000",
+			}},
+			Pagination: &cryptomkt.Pagination{Page: 0, Next: 1},
+		},
+		1: {
+			Data: []*cryptomkt.MarketOrder{{
+				ID: "M2", Type: "sell", Market: "ethclp", AvgExecutionPrice: 1500000,
+				Amount: &cryptomkt.OrderAmount{Original: 1, Executed: 0.5}, ExecutedAt: "2023-01-10T12:00:00.000000",
+			}},
+			Pagination: &cryptomkt.Pagination{Page: 1},
+		},
+	}
+	fake := &cryptomkttest.TradingAPI{
+		GetExecutedOrdersFunc: func(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error) {
+			return pages[opts.Page], nil
+		},
+	}
+	orders, err := FetchExecutedOrders(fake, "ETHCLP")
+	if err != nil || len(orders) != 2 {
+		t.Errorf("Expected 2 orders, got %d, %v", len(orders), err)
+		return
+	}
+
+	fills, err := FillsFromOrders(orders, 0.01, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(fills) != 2 || fills[1].Amount != 0.5 || fills[1].Fee != 7500 || fills[1].Market != "ETHCLP" {
+		t.Errorf("Unexpected fills %+v", fills)
+	}
+
+	transfers, err := TransfersFromTransactions([]*cryptomkt.Transaction{
+		{ID: "T1", Type: cryptomkt.TransactionTypeDeposit, Status: cryptomkt.TransactionStatusConfirmed, Amount: 2, FeeAmount: 0.1, Currency: "eth", Date: "2022-01-01T00:00:00.000000"},
+		{ID: "T2", Type: cryptomkt.TransactionTypeWithdrawal, Status: cryptomkt.TransactionStatusConfirmed, Amount: 1, FeeAmount: 0.01, Currency: "eth", Date: "2022-02-01T00:00:00.000000"},
+		{ID: "T3", Type: cryptomkt.TransactionTypeWithdrawal, Status: cryptomkt.TransactionStatusPending, Amount: 1, Currency: "eth", Date: "2022-03-01T00:00:00.000000"},
+	}, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(transfers) != 2 || transfers[0].Fee != 0 || transfers[1].Amount != -1 || transfers[1].Fee != 0.01 {
+		t.Errorf("Unexpected transfers %+v", transfers)
+	}
+
+	// The deposit is valued at the price of the day.
+	l := New(Config{Currency: "CLP", Price: func(currency string, at time.Time) (float64, error) {
+		return 800000, nil
+	}})
+	l.AddFills(fills...)
+	l.AddTransfers(transfers...)
+	r, err := l.Run()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(r.Disposals) != 1 || r.Disposals[0].LotID != "L1" || r.Disposals[0].Cost != 400000 {
+		t.Errorf("Expected disposal of the deposit lot, got %+v", r.Disposals[0])
+	}
+}
@@ -0,0 +1,67 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// csvTime is the layout of the dates of the CSV reports.
+const csvTime = "2006-01-02 15:04:05"
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// WriteDisposalsCSV writes the report by lot: one row per lot matched by a
+// disposal.
+func (r *Report) WriteDisposalsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"lot", "order", "currency", "acquired", "disposed", "amount", "cost", "proceeds", "gain"})
+	for _, d := range r.Disposals {
+		cw.Write([]string{
+			d.LotID,
+			d.OrderID,
+			d.Currency,
+			d.Acquired.Format(csvTime),
+			d.Disposed.Format(csvTime),
+			formatFloat(d.Amount),
+			formatFloat(d.Cost),
+			formatFloat(d.Proceeds),
+			formatFloat(d.Gain),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteYearsCSV writes the report by year.
+func (r *Report) WriteYearsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"year", "currency", "method", "disposals", "proceeds", "cost", "gain", "fees"})
+	for _, y := range r.Years {
+		cw.Write([]string{
+			strconv.Itoa(y.Year),
+			r.Currency,
+			r.Method.String(),
+			strconv.Itoa(y.Disposals),
+			formatFloat(y.Proceeds),
+			formatFloat(y.Cost),
+			formatFloat(y.Gain),
+			formatFloat(y.Fees),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteOpenLotsCSV writes the lots still held.
+func (r *Report) WriteOpenLotsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"lot", "currency", "acquired", "amount", "cost"})
+	for _, l := range r.OpenLots {
+		cw.Write([]string{l.ID, l.Currency, l.Acquired.Format(csvTime), formatFloat(l.Amount), formatFloat(l.Cost)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
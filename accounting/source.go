@@ -0,0 +1,101 @@
+package accounting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// dateLayout is the layout of the dates of the API.
+const dateLayout = "2006-01-02T15:04:05.999999"
+
+// FetchExecutedOrders returns the executed orders of markets, walking every
+// page of GetExecutedOrders.
+func FetchExecutedOrders(tr cryptomkt.TradingAPI, markets ...string) ([]*cryptomkt.MarketOrder, error) {
+	var orders []*cryptomkt.MarketOrder
+	for _, market := range markets {
+		opts := &cryptomkt.MarketOrderOptions{Market: market, Limit: 100}
+		for {
+			resp, err := tr.GetExecutedOrders(opts)
+			if err != nil {
+				return nil, err
+			}
+			orders = append(orders, resp.Data...)
+			if resp.Pagination == nil || int(resp.Pagination.Next) <= opts.Page {
+				break
+			}
+			opts.Page = int(resp.Pagination.Next)
+		}
+	}
+	return orders, nil
+}
+
+// FillsFromOrders returns one fill per executed order, at its average
+// execution price. The API does not report fees, they are feeRate of the
+// executed notional. Dates are read in loc, UTC when nil. Use fills of your
+// own, for example from an OrderTracker, when available.
+func FillsFromOrders(orders []*cryptomkt.MarketOrder, feeRate float64, loc *time.Location) ([]*Fill, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	fills := make([]*Fill, 0, len(orders))
+	for _, o := range orders {
+		if o.Amount == nil || o.Amount.Executed <= 0 {
+			continue
+		}
+		date := o.ExecutedAt
+		if date == "" {
+			date = o.UpdatedAt
+		}
+		at, err := time.ParseInLocation(dateLayout, date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("accounting: invalid date %q of order %s", date, o.ID)
+		}
+		price := float64(o.AvgExecutionPrice)
+		if price == 0 {
+			price = o.ExecutionPrice
+		}
+		fills = append(fills, &Fill{
+			OrderID: o.ID,
+			Time:    at,
+			Market:  strings.ToUpper(o.Market),
+			Side:    o.Type,
+			Amount:  o.Amount.Executed,
+			Price:   price,
+			Fee:     o.Amount.Executed * price * feeRate,
+		})
+	}
+	return fills, nil
+}
+
+// TransfersFromTransactions returns the confirmed deposits and withdrawals
+// of txs as transfers. Deposits are left without cost. Dates are read in
+// loc, UTC when nil.
+func TransfersFromTransactions(txs []*cryptomkt.Transaction, loc *time.Location) ([]*Transfer, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	transfers := make([]*Transfer, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Status != cryptomkt.TransactionStatusConfirmed {
+			continue
+		}
+		at, err := time.ParseInLocation(dateLayout, tx.Date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("accounting: invalid date %q of transaction %s", tx.Date, tx.ID)
+		}
+		t := &Transfer{ID: tx.ID, Time: at, Currency: strings.ToUpper(tx.Currency), Amount: tx.Amount, Fee: tx.FeeAmount}
+		switch tx.Type {
+		case cryptomkt.TransactionTypeDeposit:
+			t.Fee = 0
+		case cryptomkt.TransactionTypeWithdrawal:
+			t.Amount = -tx.Amount
+		default:
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
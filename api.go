@@ -0,0 +1,249 @@
+package cryptomkt
+
+import (
+	"context"
+	"time"
+)
+
+// PublicAPI is the set of public endpoints. PublicService implements it.
+type PublicAPI interface {
+	GetMarkets() (*MarketResponse, error)
+	GetTicker(market string) (*TickerResponse, error)
+	GetOrdersBook(opts *BooksOptions) (*BooksResponse, error)
+	GetTrades(opts *TradesOptions) (*TradesResponse, error)
+	GetPrices(opts *PricesOptions) (*PricesResponse, error)
+}
+
+// TradingAPI is the set of order and balance endpoints. PrivateService
+// implements it.
+type TradingAPI interface {
+	CreateOrder(mor *MarketOrderRequest) (*MarketOrderResponse, error)
+	CancelOrder(cor *CancelOrderRequest) (*MarketOrderResponse, error)
+	GetOrderStatus(opts *OrderStatusOption) (*MarketOrderResponse, error)
+	GetActiveOrders(opts *MarketOrderOptions) (*MarketOrdersResponse, error)
+	GetExecutedOrders(opts *MarketOrderOptions) (*MarketOrdersResponse, error)
+	GetBalance() (*BalanceResponse, error)
+}
+
+// PaymentsAPI is the set of payment endpoints. PaymentService implements it.
+type PaymentsAPI interface {
+	CreatePayment(p *PaymentRequest) (*PaymentResponse, error)
+	PaymentStatus(id string) (*PaymentResponse, error)
+	PaymentOrders(opts *PaymentOrdersOptions) (*PaymentOrdersResponse, error)
+}
+
+var (
+	_ PublicAPI   = (*PublicService)(nil)
+	_ TradingAPI  = (*PrivateService)(nil)
+	_ PaymentsAPI = (*PaymentService)(nil)
+)
+
+// Interceptor runs around every call of a decorated API. method is the
+// name of the API method, call performs it and may be called several times
+// or not at all. ctx carries values from the outer interceptors to the inner
+// ones; it does not reach the API. Interceptors add behaviour such as
+// retries, logging or metrics to any implementation of the APIs.
+type Interceptor func(ctx context.Context, method string, call func(ctx context.Context) error) error
+
+// chain composes interceptors, the first one being the outermost.
+func chain(ics []Interceptor) func(method string, call func() error) error {
+	return func(method string, call func() error) error {
+		next := func(context.Context) error { return call() }
+		for i := len(ics) - 1; i >= 0; i-- {
+			ic, inner := ics[i], next
+			next = func(ctx context.Context) error { return ic(ctx, method, inner) }
+		}
+		return next(context.Background())
+	}
+}
+
+// retryHookKey is the context key of the hook notified of retries.
+type retryHookKey struct{}
+
+// WithRetryHook returns a copy of ctx in which RetryInterceptor calls hook
+// before every retry, with the number of the attempt about to be made and
+// the error of the previous one. Interceptors set it for the inner ones.
+func WithRetryHook(ctx context.Context, hook func(attempt int, err error)) context.Context {
+	return context.WithValue(ctx, retryHookKey{}, hook)
+}
+
+// mutatingMethods holds the methods that must not be repeated blindly.
+var mutatingMethods = map[string]bool{
+	"CreateOrder":   true,
+	"CancelOrder":   true,
+	"CreatePayment": true,
+}
+
+// RetryInterceptor retries read-only calls that failed without an answer
+// from the API, with a *TransportError, up to attempts times in total,
+// waiting backoff before the first retry and doubling it after each one.
+// Calls that create or cancel orders or payments are never retried; use
+// CreateOrderIdempotent for that.
+func RetryInterceptor(attempts int, backoff time.Duration) Interceptor {
+	return func(ctx context.Context, method string, call func(ctx context.Context) error) error {
+		if mutatingMethods[method] {
+			return call(ctx)
+		}
+		hook, _ := ctx.Value(retryHookKey{}).(func(int, error))
+		var err error
+		wait := backoff
+		for attempt := 0; attempt < attempts; attempt++ {
+			if attempt > 0 {
+				if hook != nil {
+					hook(attempt+1, err)
+				}
+				time.Sleep(wait)
+				wait *= 2
+			}
+			if err = call(ctx); err == nil || !isAmbiguous(err) {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// InterceptPublic returns api with every call going through ics, the first
+// one being the outermost.
+func InterceptPublic(api PublicAPI, ics ...Interceptor) PublicAPI {
+	return &publicInterceptor{api: api, ic: chain(ics)}
+}
+
+type publicInterceptor struct {
+	api PublicAPI
+	ic  func(method string, call func() error) error
+}
+
+func (pi *publicInterceptor) GetMarkets() (resp *MarketResponse, err error) {
+	err = pi.ic("GetMarkets", func() (err error) {
+		resp, err = pi.api.GetMarkets()
+		return err
+	})
+	return resp, err
+}
+
+func (pi *publicInterceptor) GetTicker(market string) (resp *TickerResponse, err error) {
+	err = pi.ic("GetTicker", func() (err error) {
+		resp, err = pi.api.GetTicker(market)
+		return err
+	})
+	return resp, err
+}
+
+func (pi *publicInterceptor) GetOrdersBook(opts *BooksOptions) (resp *BooksResponse, err error) {
+	err = pi.ic("GetOrdersBook", func() (err error) {
+		resp, err = pi.api.GetOrdersBook(opts)
+		return err
+	})
+	return resp, err
+}
+
+func (pi *publicInterceptor) GetTrades(opts *TradesOptions) (resp *TradesResponse, err error) {
+	err = pi.ic("GetTrades", func() (err error) {
+		resp, err = pi.api.GetTrades(opts)
+		return err
+	})
+	return resp, err
+}
+
+func (pi *publicInterceptor) GetPrices(opts *PricesOptions) (resp *PricesResponse, err error) {
+	err = pi.ic("GetPrices", func() (err error) {
+		resp, err = pi.api.GetPrices(opts)
+		return err
+	})
+	return resp, err
+}
+
+// InterceptTrading returns api with every call going through ics, the first
+// one being the outermost.
+func InterceptTrading(api TradingAPI, ics ...Interceptor) TradingAPI {
+	return &tradingInterceptor{api: api, ic: chain(ics)}
+}
+
+type tradingInterceptor struct {
+	api TradingAPI
+	ic  func(method string, call func() error) error
+}
+
+func (ti *tradingInterceptor) CreateOrder(mor *MarketOrderRequest) (resp *MarketOrderResponse, err error) {
+	err = ti.ic("CreateOrder", func() (err error) {
+		resp, err = ti.api.CreateOrder(mor)
+		return err
+	})
+	return resp, err
+}
+
+func (ti *tradingInterceptor) CancelOrder(cor *CancelOrderRequest) (resp *MarketOrderResponse, err error) {
+	err = ti.ic("CancelOrder", func() (err error) {
+		resp, err = ti.api.CancelOrder(cor)
+		return err
+	})
+	return resp, err
+}
+
+func (ti *tradingInterceptor) GetOrderStatus(opts *OrderStatusOption) (resp *MarketOrderResponse, err error) {
+	err = ti.ic("GetOrderStatus", func() (err error) {
+		resp, err = ti.api.GetOrderStatus(opts)
+		return err
+	})
+	return resp, err
+}
+
+func (ti *tradingInterceptor) GetActiveOrders(opts *MarketOrderOptions) (resp *MarketOrdersResponse, err error) {
+	err = ti.ic("GetActiveOrders", func() (err error) {
+		resp, err = ti.api.GetActiveOrders(opts)
+		return err
+	})
+	return resp, err
+}
+
+func (ti *tradingInterceptor) GetExecutedOrders(opts *MarketOrderOptions) (resp *MarketOrdersResponse, err error) {
+	err = ti.ic("GetExecutedOrders", func() (err error) {
+		resp, err = ti.api.GetExecutedOrders(opts)
+		return err
+	})
+	return resp, err
+}
+
+func (ti *tradingInterceptor) GetBalance() (resp *BalanceResponse, err error) {
+	err = ti.ic("GetBalance", func() (err error) {
+		resp, err = ti.api.GetBalance()
+		return err
+	})
+	return resp, err
+}
+
+// InterceptPayments returns api with every call going through ics, the
+// first one being the outermost.
+func InterceptPayments(api PaymentsAPI, ics ...Interceptor) PaymentsAPI {
+	return &paymentsInterceptor{api: api, ic: chain(ics)}
+}
+
+type paymentsInterceptor struct {
+	api PaymentsAPI
+	ic  func(method string, call func() error) error
+}
+
+func (pi *paymentsInterceptor) CreatePayment(p *PaymentRequest) (resp *PaymentResponse, err error) {
+	err = pi.ic("CreatePayment", func() (err error) {
+		resp, err = pi.api.CreatePayment(p)
+		return err
+	})
+	return resp, err
+}
+
+func (pi *paymentsInterceptor) PaymentStatus(id string) (resp *PaymentResponse, err error) {
+	err = pi.ic("PaymentStatus", func() (err error) {
+		resp, err = pi.api.PaymentStatus(id)
+		return err
+	})
+	return resp, err
+}
+
+func (pi *paymentsInterceptor) PaymentOrders(opts *PaymentOrdersOptions) (resp *PaymentOrdersResponse, err error) {
+	err = pi.ic("PaymentOrders", func() (err error) {
+		resp, err = pi.api.PaymentOrders(opts)
+		return err
+	})
+	return resp, err
+}
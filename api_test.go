@@ -0,0 +1,90 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_InterceptPublic(t *testing.T) {
+	requests := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// The first request drops the connection.
+		if requests == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write(getMarketsResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, method string, call func(ctx context.Context) error) error {
+			order = append(order, name+" "+method)
+			return call(ctx)
+		}
+	}
+	api := InterceptPublic(
+		&PublicService{client: &httpClient{client: httpCli}},
+		trace("outer"),
+		RetryInterceptor(2, 0),
+		trace("inner"),
+	)
+
+	resp, err := api.GetMarkets()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(resp.Data) == 0 {
+		t.Errorf("Expected markets")
+	}
+	expected := []string{"outer GetMarkets", "inner GetMarkets", "inner GetMarkets"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected %v, got %v", expected, order)
+	}
+}
+
+func Test_RetryInterceptor(t *testing.T) {
+	retry := RetryInterceptor(3, 0)
+	cases := []struct {
+		name     string
+		method   string
+		err      error
+		expected int
+	}{
+		{"ambiguous read", "GetBalance", &TransportError{Err: errors.New("connection reset")}, 3},
+		{"local error", "GetBalance", errors.New("rate limiter: context canceled"), 1},
+		{"api error", "GetBalance", &APIError{Status: "error", Message: "invalid"}, 1},
+		{"mutating", "CreateOrder", &TransportError{Err: errors.New("connection reset")}, 1},
+		{"success", "GetBalance", nil, 1},
+	}
+	for _, c := range cases {
+		calls, retries := 0, 0
+		ctx := WithRetryHook(context.Background(), func(attempt int, err error) {
+			retries++
+			if attempt != retries+1 || err != c.err {
+				t.Errorf("%s: Unexpected retry hook call %d, %v", c.name, attempt, err)
+			}
+		})
+		err := retry(ctx, c.method, func(context.Context) error {
+			calls++
+			return c.err
+		})
+		if err != c.err {
+			t.Errorf("%s: Expected error %v, got %v", c.name, c.err, err)
+		}
+		if calls != c.expected {
+			t.Errorf("%s: Expected %d calls, got %d", c.name, c.expected, calls)
+		}
+		if retries != calls-1 {
+			t.Errorf("%s: Expected %d retries, got %d", c.name, calls-1, retries)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package cryptomkt
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backfillDateLayout is the layout of the dates sent to the trades endpoint,
+// which filters by day.
+const backfillDateLayout = "2006-01-02"
+
+// TradeSink receives the trades of a backfill, one window at a time and in
+// chronological order.
+type TradeSink interface {
+	WriteTrades(trades []*Trade) error
+	// Flush is called after every window, before the checkpoint is saved.
+	Flush() error
+}
+
+// BackfillOptions configures a trade backfill.
+type BackfillOptions struct {
+	Market string
+	// Start and End bound the trades to fetch, End excluded. Both are
+	// truncated to the day, in UTC.
+	Start time.Time
+	End   time.Time
+	// Window is the period requested at once, rounded to whole days. One day
+	// by default.
+	Window time.Duration
+	// Limit is the page size. 100 by default.
+	Limit int
+	// MaxPages is the number of pages walked in a window before splitting it
+	// in half. Windows of one day are never split. Unlimited when zero.
+	MaxPages int
+	// Checkpoint is the path of the file recording the progress of the
+	// backfill. When it exists, the backfill resumes after the last window
+	// written.
+	Checkpoint string
+}
+
+// backfillCheckpoint represents the progress of a backfill.
+type backfillCheckpoint struct {
+	Market string `json:"market"`
+	// Next is the start of the first window not yet written.
+	Next string `json:"next"`
+}
+
+// Backfill fetches every trade of opts.Market between opts.Start and opts.End
+// and writes them to sink window by window, deduplicated by Tid. It returns
+// the number of trades written. ctx bounds every request. A window interrupted before its checkpoint
+// is saved is fetched again on resume, so file sinks may repeat its trades;
+// SQLSink ignores them.
+func (ps *PublicService) Backfill(ctx context.Context, opts *BackfillOptions, sink TradeSink) (int, error) {
+	if opts.Market == "" {
+		return 0, errors.New("cryptopay: market is required")
+	}
+	day := 24 * time.Hour
+	start, end := opts.Start.UTC().Truncate(day), opts.End.UTC().Truncate(day)
+	window := opts.Window.Truncate(day)
+	if window <= 0 {
+		window = day
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	if opts.Checkpoint != "" {
+		next, err := loadCheckpoint(opts.Checkpoint, opts.Market)
+		if err != nil {
+			return 0, err
+		}
+		if next.After(start) {
+			start = next
+		}
+	}
+
+	b := &backfill{ps: ps, market: strings.ToUpper(opts.Market), limit: limit, maxPages: opts.MaxPages}
+	written := 0
+	for start.Before(end) {
+		wend := start.Add(window)
+		if wend.After(end) {
+			wend = end
+		}
+
+		trades, err := b.window(ctx, start, wend)
+		if err != nil {
+			return written, err
+		}
+		// Windows do not overlap: only the pages of a window can repeat
+		// trades, when new trades shift them.
+		seen := make(map[string]bool, len(trades))
+		fresh := make([]*Trade, 0, len(trades))
+		for _, t := range trades {
+			if !seen[t.Tid] {
+				seen[t.Tid] = true
+				fresh = append(fresh, t)
+			}
+		}
+		sort.SliceStable(fresh, func(i, j int) bool {
+			return fresh[i].Timestamp < fresh[j].Timestamp
+		})
+
+		if err := sink.WriteTrades(fresh); err != nil {
+			return written, err
+		}
+		if err := sink.Flush(); err != nil {
+			return written, err
+		}
+		written += len(fresh)
+
+		if opts.Checkpoint != "" {
+			if err := saveCheckpoint(opts.Checkpoint, opts.Market, wend); err != nil {
+				return written, err
+			}
+		}
+		start = wend
+	}
+
+	return written, nil
+}
+
+// backfill holds the settings shared by the windows of a backfill.
+type backfill struct {
+	ps       *PublicService
+	market   string
+	limit    int
+	maxPages int
+}
+
+// window returns the trades between start and end, splitting the window in
+// two when it has more than maxPages pages.
+func (b *backfill) window(ctx context.Context, start, end time.Time) ([]*Trade, error) {
+	opts := &TradesOptions{
+		Market:    b.market,
+		StartDate: start.Format(backfillDateLayout),
+		EndDate:   end.Format(backfillDateLayout),
+		Limit:     b.limit,
+	}
+
+	var trades []*Trade
+	for pages := 0; ; pages++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		days := int(end.Sub(start).Hours() / 24)
+		if b.maxPages > 0 && pages == b.maxPages && days > 1 {
+			mid := start.Add(time.Duration(days/2) * 24 * time.Hour)
+			first, err := b.window(ctx, start, mid)
+			if err != nil {
+				return nil, err
+			}
+			second, err := b.window(ctx, mid, end)
+			if err != nil {
+				return nil, err
+			}
+			return append(first, second...), nil
+		}
+
+		tr, err := b.ps.getTrades(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tr.Data {
+			ts, err := time.Parse(dateLayout, t.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("cryptopay: invalid trade timestamp %q", t.Timestamp)
+			}
+			// The endpoint filters by day, keep only the trades of the window.
+			if !ts.Before(start) && ts.Before(end) {
+				trades = append(trades, t)
+			}
+		}
+		if tr.Pagination == nil || int(tr.Pagination.Next) <= opts.Page {
+			break
+		}
+		opts.Page = int(tr.Pagination.Next)
+	}
+
+	return trades, nil
+}
+
+// loadCheckpoint returns the start of the next window recorded at path, or
+// the zero time when there is no checkpoint yet.
+func loadCheckpoint(path, market string) (time.Time, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var cp backfillCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return time.Time{}, fmt.Errorf("cryptopay: invalid checkpoint %s, %v", path, err)
+	}
+	if !strings.EqualFold(cp.Market, market) {
+		return time.Time{}, fmt.Errorf("cryptopay: checkpoint %s belongs to market %s", path, cp.Market)
+	}
+	return time.Parse(backfillDateLayout, cp.Next)
+}
+
+// saveCheckpoint atomically records next as the start of the next window.
+func saveCheckpoint(path, market string, next time.Time) error {
+	b, err := json.Marshal(backfillCheckpoint{Market: strings.ToUpper(market), Next: next.Format(backfillDateLayout)})
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// csvHeader is the header written by CSVSink.
+var csvHeader = []string{"tid", "market", "timestamp", "market_taker", "price", "amount"}
+
+// CSVSink writes trades as CSV rows.
+type CSVSink struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVSink returns a sink writing to w. When header is true the column
+// names are written before the first row; leave it false when appending to
+// an existing file.
+func NewCSVSink(w io.Writer, header bool) *CSVSink {
+	return &CSVSink{w: csv.NewWriter(w), header: header}
+}
+
+// WriteTrades implements TradeSink interface.
+func (cs *CSVSink) WriteTrades(trades []*Trade) error {
+	if cs.header {
+		if err := cs.w.Write(csvHeader); err != nil {
+			return err
+		}
+		cs.header = false
+	}
+	for _, t := range trades {
+		if err := cs.w.Write([]string{t.Tid, t.Market, t.Timestamp, t.MarketTaker, t.Price, t.Amount}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush implements TradeSink interface.
+func (cs *CSVSink) Flush() error {
+	cs.w.Flush()
+	return cs.w.Error()
+}
+
+// JSONLSink writes trades as JSON Lines.
+type JSONLSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONLSink returns a sink writing to w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{enc: json.NewEncoder(w)}
+}
+
+// WriteTrades implements TradeSink interface.
+func (js *JSONLSink) WriteTrades(trades []*Trade) error {
+	for _, t := range trades {
+		if err := js.enc.Encode(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush implements TradeSink interface.
+func (js *JSONLSink) Flush() error {
+	return nil
+}
+
+// SQLSink writes trades to a "trades" table keyed by Tid, ignoring trades
+// already stored. The statements are meant for SQLite; db may use any driver
+// accepting them.
+type SQLSink struct {
+	db *sql.DB
+}
+
+// NewSQLSink creates the trades table in db if needed.
+func NewSQLSink(db *sql.DB) (*SQLSink, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS trades (
+		tid TEXT PRIMARY KEY,
+		market TEXT NOT NULL,
+		timestamp TEXT NOT NULL,
+		market_taker TEXT,
+		price TEXT,
+		amount TEXT
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLSink{db: db}, nil
+}
+
+// WriteTrades implements TradeSink interface. Every window is written in one
+// transaction.
+func (ss *SQLSink) WriteTrades(trades []*Trade) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO trades (tid, market, timestamp, market_taker, price, amount) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range trades {
+		if _, err := stmt.Exec(t.Tid, t.Market, t.Timestamp, t.MarketTaker, t.Price, t.Amount); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Flush implements TradeSink interface.
+func (ss *SQLSink) Flush() error {
+	return nil
+}
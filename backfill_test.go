@@ -0,0 +1,313 @@
+package cryptomkt
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var backfillTrades = []*Trade{
+	{Tid: "1", Market: "ETHCLP", Timestamp: "2017-09-01T10:00:00.000000", MarketTaker: "buy", Price: "10000", Amount: "0.1"},
+	{Tid: "2", Market: "ETHCLP", Timestamp: "2017-09-01T11:00:00.000000", MarketTaker: "sell", Price: "9990", Amount: "0.2"},
+	{Tid: "3", Market: "ETHCLP", Timestamp: "2017-09-01T12:00:00.000000", MarketTaker: "buy", Price: "10010", Amount: "0.3"},
+	{Tid: "4", Market: "ETHCLP", Timestamp: "2017-09-02T10:00:00.000000", MarketTaker: "buy", Price: "10020", Amount: "0.4"},
+	{Tid: "5", Market: "ETHCLP", Timestamp: "2017-09-03T10:00:00.000000", MarketTaker: "sell", Price: "10030", Amount: "0.5"},
+	{Tid: "6", Market: "ETHCLP", Timestamp: "2017-09-04T10:00:00.000000", MarketTaker: "buy", Price: "10040", Amount: "0.6"},
+}
+
+// backfillHandler serves backfillTrades filtered by day, end included, in
+// pages of two trades overlapping by one, the newest trade first.
+func backfillHandler(requests *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		*requests = append(*requests, q.Get("start")+"/"+q.Get("end")+"/"+q.Get("page"))
+
+		var matching []*Trade
+		for i := len(backfillTrades) - 1; i >= 0; i-- {
+			day := backfillTrades[i].Timestamp[:10]
+			if day >= q.Get("start") && day <= q.Get("end") {
+				matching = append(matching, backfillTrades[i])
+			}
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		end := page + 2
+		if end > len(matching) {
+			end = len(matching)
+		}
+		next := "null"
+		if end < len(matching) {
+			next = strconv.Itoa(page + 1)
+		}
+
+		data, _ := json.Marshal(matching[page:end])
+		fmt.Fprintf(w, `{"status": "success", "pagination": {"page": %d, "next": "%s"}, "data": %s}`, page, next, data)
+	}
+}
+
+func Test_Backfill(t *testing.T) {
+	var requests []string
+	httpCli, teardown := testingHTTPClient(backfillHandler(&requests))
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	var buf bytes.Buffer
+	checkpoint := filepath.Join(t.TempDir(), "ethclp.checkpoint")
+	opts := &BackfillOptions{
+		Market:     "ETHCLP",
+		Start:      time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC),
+		End:        time.Date(2017, 9, 4, 0, 0, 0, 0, time.UTC),
+		Window:     72 * time.Hour,
+		MaxPages:   2,
+		Checkpoint: checkpoint,
+	}
+	n, err := ps.Backfill(context.Background(), opts, NewCSVSink(&buf, true))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 trades, got %d", n)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"tid,market,timestamp,market_taker,price,amount",
+		"1,ETHCLP,2017-09-01T10:00:00.000000,buy,10000,0.1",
+		"2,ETHCLP,2017-09-01T11:00:00.000000,sell,9990,0.2",
+		"3,ETHCLP,2017-09-01T12:00:00.000000,buy,10010,0.3",
+		"4,ETHCLP,2017-09-02T10:00:00.000000,buy,10020,0.4",
+		"5,ETHCLP,2017-09-03T10:00:00.000000,sell,10030,0.5",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected rows\n%s\ngot\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+	if len(requests) != 7 || requests[2] != "2017-09-01/2017-09-02/" {
+		t.Errorf("Expected the window to be split after 2 pages, got requests %v", requests)
+	}
+
+	// Extending the backfill resumes after the checkpoint.
+	requests = nil
+	buf.Reset()
+	opts.End = time.Date(2017, 9, 5, 0, 0, 0, 0, time.UTC)
+	n, err = ps.Backfill(context.Background(), opts, NewJSONLSink(&buf))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if n != 1 || !strings.Contains(buf.String(), `"tid":"6"`) {
+		t.Errorf("Expected only trade 6 after resuming, got %d: %s", n, buf.String())
+	}
+	if len(requests) != 1 || !strings.HasPrefix(requests[0], "2017-09-04/") {
+		t.Errorf("Expected a single request from 2017-09-04, got %v", requests)
+	}
+}
+
+func Test_BackfillCheckpointMarket(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	if err := saveCheckpoint(checkpoint, "BTCCLP", time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if _, err := loadCheckpoint(checkpoint, "ETHCLP"); err == nil {
+		t.Errorf("Expected error for checkpoint of another market")
+	}
+}
+
+func Test_SQLSink(t *testing.T) {
+	db, err := sql.Open("backfilltest", t.Name())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	defer db.Close()
+	sink, err := NewSQLSink(db)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	var requests []string
+	httpCli, teardown := testingHTTPClient(backfillHandler(&requests))
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+	opts := &BackfillOptions{
+		Market: "ETHCLP",
+		Start:  time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2017, 9, 3, 0, 0, 0, 0, time.UTC),
+	}
+	if n, err := ps.Backfill(context.Background(), opts, sink); err != nil || n != 4 {
+		t.Errorf("Expected 4 trades, got %d and %v", n, err)
+	}
+	// A window fetched again, as on resume, is not stored twice.
+	if err := sink.WriteTrades(backfillTrades[2:]); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	stubMu.Lock()
+	table := stubTables[t.Name()]
+	stubMu.Unlock()
+	if len(table.rows) != len(backfillTrades) {
+		t.Errorf("Expected %d trades stored, got %d", len(backfillTrades), len(table.rows))
+	}
+	if row := table.rows["2"]; len(row) != 6 || row[1] != "ETHCLP" || row[3] != "sell" || row[4] != "9990" {
+		t.Errorf("Expected trade 2 to be stored, got %v", row)
+	}
+	if table.commits != 3 {
+		t.Errorf("Expected one transaction per write, got %d", table.commits)
+	}
+}
+
+func Test_BackfillContext(t *testing.T) {
+	block := make(chan struct{})
+	httpCli, teardown := testingHTTPClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer teardown()
+	defer close(block)
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	opts := &BackfillOptions{
+		Market: "ETHCLP",
+		Start:  time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2017, 9, 2, 0, 0, 0, 0, time.UTC),
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := ps.Backfill(ctx, opts, NewJSONLSink(ioutil.Discard))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected the page request to stop with ctx")
+	}
+}
+
+// stubTables holds the trades table of every stub database, by name.
+var (
+	stubMu     sync.Mutex
+	stubTables = make(map[string]*stubTable)
+)
+
+func init() {
+	sql.Register("backfilltest", stubDriver{})
+}
+
+// stubDriver is a database/sql driver understanding the statements of
+// SQLSink. Its trades table ignores rows whose tid is already stored, like
+// the primary key and INSERT OR IGNORE of SQLite.
+type stubDriver struct{}
+
+type stubTable struct {
+	mu      sync.Mutex
+	rows    map[string][]driver.Value
+	pending map[string][]driver.Value
+	commits int
+}
+
+func (stubDriver) Open(name string) (driver.Conn, error) {
+	stubMu.Lock()
+	defer stubMu.Unlock()
+	table, ok := stubTables[name]
+	if !ok {
+		table = &stubTable{rows: make(map[string][]driver.Value)}
+		stubTables[name] = table
+	}
+	return &stubConn{table: table}, nil
+}
+
+type stubConn struct {
+	table *stubTable
+}
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS trades (\n\t\ttid TEXT PRIMARY KEY,"):
+		return &stubStmt{}, nil
+	case strings.HasPrefix(query, "INSERT OR IGNORE INTO trades (tid, market, timestamp, market_taker, price, amount)"):
+		return &stubStmt{table: c.table}, nil
+	}
+	return nil, fmt.Errorf("unexpected statement %q", query)
+}
+
+func (c *stubConn) Close() error {
+	return nil
+}
+
+func (c *stubConn) Begin() (driver.Tx, error) {
+	c.table.mu.Lock()
+	c.table.pending = make(map[string][]driver.Value)
+	c.table.mu.Unlock()
+	return c, nil
+}
+
+func (c *stubConn) Commit() error {
+	c.table.mu.Lock()
+	defer c.table.mu.Unlock()
+	for tid, row := range c.table.pending {
+		c.table.rows[tid] = row
+	}
+	c.table.pending = nil
+	c.table.commits++
+	return nil
+}
+
+func (c *stubConn) Rollback() error {
+	c.table.mu.Lock()
+	c.table.pending = nil
+	c.table.mu.Unlock()
+	return nil
+}
+
+type stubStmt struct {
+	table *stubTable
+}
+
+func (s *stubStmt) Close() error {
+	return nil
+}
+
+func (s *stubStmt) NumInput() int {
+	if s.table == nil {
+		return 0
+	}
+	return 6
+}
+
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.table == nil {
+		return driver.RowsAffected(0), nil
+	}
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+	tid := args[0].(string)
+	if _, ok := s.table.rows[tid]; ok {
+		return driver.RowsAffected(0), nil
+	}
+	if _, ok := s.table.pending[tid]; ok {
+		return driver.RowsAffected(0), nil
+	}
+	s.table.pending[tid] = args
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
@@ -0,0 +1,246 @@
+// Package backtest replays recorded trades and books against a Strategy.
+//
+// The strategy trades through Trader, the same methods it would call on a
+// *cryptomkt.PrivateService. Orders reach the simulated book after the
+// configured latency and are filled as takers against the book at that
+// moment. Resting orders are filled as makers, at their own price, by later
+// trades and book snapshots crossing them. Fees are charged in the quote
+// currency.
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// dateLayout is the layout of the dates of the API.
+const dateLayout = "2006-01-02T15:04:05.999999"
+
+// Config represents the configuration of a backtest.
+type Config struct {
+	// Balances holds the initial balance of every currency.
+	Balances map[string]float64
+	// MakerFee and TakerFee are the fee rates of resting and taking fills.
+	MakerFee float64
+	TakerFee float64
+	// Latency delays orders and cancellations.
+	Latency time.Duration
+	// TimerInterval is the period of Strategy.OnTimer. No timer when zero.
+	TimerInterval time.Duration
+	// Quote is the currency the report is valued in, for example CLP.
+	Quote string
+}
+
+// BookSnapshot represents one side of the book of a market at a time.
+type BookSnapshot struct {
+	Time   time.Time
+	Market string
+	// Side of the book, buy or sell.
+	Side   string
+	Levels []*cryptomkt.Book
+}
+
+// Strategy receives the recorded data in chronological order and trades
+// with tr. Returning an error stops the backtest.
+type Strategy interface {
+	OnTrade(tr Trader, t *cryptomkt.Trade) error
+	OnBook(tr Trader, b *BookSnapshot) error
+	OnTimer(tr Trader, now time.Time) error
+}
+
+// EquityPoint represents the value of the account at a time.
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// Report represents the results of a backtest.
+type Report struct {
+	InitialEquity float64
+	FinalEquity   float64
+	// PnL is FinalEquity minus InitialEquity, net of fees.
+	PnL float64
+	// Return is PnL relative to InitialEquity.
+	Return float64
+	// MaxDrawdown is the largest relative fall of equity from a peak.
+	MaxDrawdown float64
+	Fees        float64
+	// Volume is the traded notional in quote currency.
+	Volume float64
+	// Turnover is Volume relative to InitialEquity.
+	Turnover float64
+	Orders   int
+	// FilledOrders counts the fully executed orders.
+	FilledOrders int
+	// FillRate is the executed amount relative to the ordered amount.
+	FillRate float64
+	Equity   []EquityPoint
+}
+
+// event is a recorded trade or book snapshot.
+type event struct {
+	at    time.Time
+	trade *cryptomkt.Trade
+	book  *BookSnapshot
+}
+
+// Engine replays recorded data.
+type Engine struct {
+	cfg    Config
+	events []*event
+}
+
+// New returns an engine configured with cfg.
+func New(cfg Config) *Engine {
+	cfg.Quote = strings.ToUpper(cfg.Quote)
+	return &Engine{cfg: cfg}
+}
+
+// AddTrades records trades, in any order.
+func (e *Engine) AddTrades(trades ...*cryptomkt.Trade) error {
+	for _, t := range trades {
+		at, err := time.Parse(dateLayout, t.Timestamp)
+		if err != nil {
+			return fmt.Errorf("backtest: invalid trade timestamp %q", t.Timestamp)
+		}
+		e.events = append(e.events, &event{at: at, trade: t})
+	}
+	return nil
+}
+
+// AddBooks records book snapshots, in any order.
+func (e *Engine) AddBooks(books ...*BookSnapshot) {
+	for _, b := range books {
+		e.events = append(e.events, &event{at: b.Time, book: b})
+	}
+}
+
+// Run replays the recorded data against s and returns the report. Books
+// come before trades of the same time.
+func (e *Engine) Run(s Strategy) (*Report, error) {
+	if e.cfg.Quote == "" {
+		return nil, errors.New("backtest: quote currency is required")
+	}
+	if len(e.events) == 0 {
+		return nil, errors.New("backtest: no recorded data")
+	}
+
+	events := append([]*event(nil), e.events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		return events[i].book != nil && events[j].book == nil
+	})
+
+	b := newBroker(&e.cfg)
+	prices := make(map[string]float64)
+	r := &Report{}
+	peak := 0.0
+	mark := func(at time.Time) {
+		eq := e.equity(b, prices)
+		r.Equity = append(r.Equity, EquityPoint{Time: at, Equity: eq})
+		peak = math.Max(peak, eq)
+		if peak > 0 {
+			r.MaxDrawdown = math.Max(r.MaxDrawdown, (peak-eq)/peak)
+		}
+	}
+
+	// The account starts valued at the first price of every market.
+	for _, ev := range events {
+		if ev.trade != nil {
+			if _, ok := prices[strings.ToUpper(ev.trade.Market)]; !ok {
+				prices[strings.ToUpper(ev.trade.Market)] = parsePrice(ev.trade.Price)
+			}
+		}
+	}
+	r.InitialEquity = e.equity(b, prices)
+
+	nextTimer := events[0].at
+	for _, ev := range events {
+		// Timers and pending actions due before the event, in time order.
+		for e.cfg.TimerInterval > 0 && !nextTimer.After(ev.at) {
+			b.runActions(nextTimer)
+			b.now = nextTimer
+			if err := s.OnTimer(b, nextTimer); err != nil {
+				return nil, err
+			}
+			nextTimer = nextTimer.Add(e.cfg.TimerInterval)
+		}
+		b.runActions(ev.at)
+		b.now = ev.at
+
+		switch {
+		case ev.book != nil:
+			if err := b.onBook(ev.book); err != nil {
+				return nil, err
+			}
+			if err := s.OnBook(b, ev.book); err != nil {
+				return nil, err
+			}
+		case ev.trade != nil:
+			if err := b.onTrade(ev.trade); err != nil {
+				return nil, err
+			}
+			prices[strings.ToUpper(ev.trade.Market)] = parsePrice(ev.trade.Price)
+			if err := s.OnTrade(b, ev.trade); err != nil {
+				return nil, err
+			}
+			mark(ev.at)
+		}
+	}
+
+	r.FinalEquity = e.equity(b, prices)
+	r.PnL = r.FinalEquity - r.InitialEquity
+	r.Fees = b.acct.Fees
+	r.Volume = b.acct.Volume
+	if r.InitialEquity > 0 {
+		r.Return = r.PnL / r.InitialEquity
+		r.Turnover = r.Volume / r.InitialEquity
+	}
+
+	ordered, executed := 0.0, 0.0
+	for _, o := range b.orders {
+		r.Orders++
+		if o.Status == "executed" {
+			r.FilledOrders++
+		}
+		ordered += o.Original
+		executed += o.Executed
+	}
+	if ordered > 0 {
+		r.FillRate = executed / ordered
+	}
+
+	return r, nil
+}
+
+// equity values the balances of b in the quote currency with prices, the
+// last price of every market. Currencies without a market are ignored.
+func (e *Engine) equity(b *broker, prices map[string]float64) float64 {
+	total := 0.0
+	for _, c := range b.acct.Wallets() {
+		if c == e.cfg.Quote {
+			total += b.acct.Balance(c)
+			continue
+		}
+		if price, ok := prices[c+e.cfg.Quote]; ok {
+			total += b.acct.Balance(c) * price
+		}
+	}
+	return total
+}
+
+// parsePrice parses a trade price. Invalid prices are reported by the
+// broker, they are zero here.
+func parsePrice(s string) float64 {
+	price, _ := strconv.ParseFloat(s, 64)
+	return price
+}
@@ -0,0 +1,155 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// roundTrip buys on the first trade and offers the position higher on the
+// second one.
+type roundTrip struct {
+	trades int
+	timers int
+	err    error
+}
+
+func (s *roundTrip) OnTrade(tr Trader, t *cryptomkt.Trade) error {
+	s.trades++
+	switch s.trades {
+	case 1:
+		_, s.err = tr.CreateOrder(&cryptomkt.MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1, Price: 10000})
+	case 2:
+		_, s.err = tr.CreateOrder(&cryptomkt.MarketOrderRequest{Market: "ETHCLP", Type: "sell", Amount: 1, Price: 11000})
+	}
+	return s.err
+}
+
+func (s *roundTrip) OnBook(tr Trader, b *BookSnapshot) error {
+	return nil
+}
+
+func (s *roundTrip) OnTimer(tr Trader, now time.Time) error {
+	s.timers++
+	return nil
+}
+
+func Test_Run(t *testing.T) {
+	start := time.Date(2017, 9, 1, 10, 0, 0, 0, time.UTC)
+	e := New(Config{
+		Balances:      map[string]float64{"CLP": 100000},
+		TakerFee:      0.01,
+		Latency:       time.Second,
+		TimerInterval: time.Minute,
+		Quote:         "CLP",
+	})
+	e.AddBooks(
+		&BookSnapshot{Time: start, Market: "ETHCLP", Side: "sell", Levels: []*cryptomkt.Book{{Price: "10000", Amount: "1"}}},
+		&BookSnapshot{Time: start, Market: "ETHCLP", Side: "buy", Levels: []*cryptomkt.Book{{Price: "9900", Amount: "1"}}},
+	)
+	err := e.AddTrades(
+		&cryptomkt.Trade{Tid: "4", Market: "ETHCLP", Price: "10500", Amount: "1", Timestamp: "2017-09-01T10:02:00"},
+		&cryptomkt.Trade{Tid: "1", Market: "ETHCLP", Price: "10000", Amount: "0.1", Timestamp: "2017-09-01T10:00:00"},
+		&cryptomkt.Trade{Tid: "2", Market: "ETHCLP", Price: "10000", Amount: "0.01", Timestamp: "2017-09-01T10:00:05"},
+		&cryptomkt.Trade{Tid: "3", Market: "ETHCLP", Price: "11000", Amount: "0.5", Timestamp: "2017-09-01T10:01:00"},
+	)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	s := &roundTrip{}
+	r, err := e.Run(s)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	cases := []struct {
+		name          string
+		got, expected float64
+	}{
+		{"initial equity", r.InitialEquity, 100000},
+		{"final equity", r.FinalEquity, 100650},
+		{"pnl", r.PnL, 650},
+		{"fees", r.Fees, 100},
+		{"volume", r.Volume, 15500},
+		{"turnover", r.Turnover, 0.155},
+		{"fill rate", r.FillRate, 0.75},
+		{"max drawdown", r.MaxDrawdown, 250.0 / 100900},
+	}
+	for _, c := range cases {
+		if math.Abs(c.got-c.expected) > 1e-9 {
+			t.Errorf("Expected %s %v, got %v", c.name, c.expected, c.got)
+		}
+	}
+	if r.Orders != 2 || r.FilledOrders != 1 {
+		t.Errorf("Expected 2 orders and 1 filled, got %d and %d", r.Orders, r.FilledOrders)
+	}
+	if s.timers != 3 {
+		t.Errorf("Expected 3 timers, got %d", s.timers)
+	}
+	if len(r.Equity) != 4 {
+		t.Errorf("Expected 4 equity points, got %d", len(r.Equity))
+	}
+}
+
+func Test_RunInsufficientFunds(t *testing.T) {
+	e := New(Config{Balances: map[string]float64{"CLP": 1000}, Quote: "CLP"})
+	e.AddTrades(&cryptomkt.Trade{Tid: "1", Market: "ETHCLP", Price: "10000", Amount: "1", Timestamp: "2017-09-01T10:00:00"})
+
+	s := &roundTrip{}
+	if _, err := e.Run(s); err == nil {
+		t.Errorf("Expected insufficient funds error")
+	}
+	if apiErr, ok := s.err.(*cryptomkt.APIError); !ok || apiErr.Message != "insufficient_funds" {
+		t.Errorf("Expected insufficient funds error, got %v", s.err)
+	}
+}
+
+// bothSides rests a buy and a sell at the same price on the first trade.
+type bothSides struct {
+	trades int
+}
+
+func (s *bothSides) OnTrade(tr Trader, t *cryptomkt.Trade) error {
+	s.trades++
+	if s.trades > 1 {
+		return nil
+	}
+	for _, typ := range []string{"buy", "sell"} {
+		if _, err := tr.CreateOrder(&cryptomkt.MarketOrderRequest{Market: "ETHCLP", Type: typ, Amount: 1, Price: 10000}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *bothSides) OnBook(tr Trader, b *BookSnapshot) error {
+	return nil
+}
+
+func (s *bothSides) OnTimer(tr Trader, now time.Time) error {
+	return nil
+}
+
+func Test_RunTradeLiquidity(t *testing.T) {
+	e := New(Config{Balances: map[string]float64{"CLP": 100000, "ETH": 1}, Quote: "CLP"})
+	e.AddTrades(
+		&cryptomkt.Trade{Tid: "1", Market: "ETHCLP", Price: "10000", Amount: "0.1", Timestamp: "2017-09-01T10:00:00"},
+		&cryptomkt.Trade{Tid: "2", Market: "ETHCLP", MarketTaker: "sell", Price: "10000", Amount: "0.5", Timestamp: "2017-09-01T10:00:10"},
+		&cryptomkt.Trade{Tid: "3", Market: "ETHCLP", Price: "10000", Amount: "0.4", Timestamp: "2017-09-01T10:00:20"},
+	)
+
+	r, err := e.Run(&bothSides{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	// The taker sell only fills the buy, the last trade is shared.
+	if math.Abs(r.Volume-9000) > 1e-9 || math.Abs(r.FillRate-0.45) > 1e-9 {
+		t.Errorf("Expected 9000 volume and 0.45 fill rate, got %v and %v", r.Volume, r.FillRate)
+	}
+}
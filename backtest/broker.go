@@ -0,0 +1,287 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+	"github.com/Finciero/go-cryptomkt/internal/matching"
+)
+
+// Trader is the part of *cryptomkt.PrivateService strategies trade with.
+// During a backtest it is implemented by the simulated broker.
+type Trader = cryptomkt.TradingAPI
+
+var _ Trader = (*broker)(nil)
+
+// order is a simulated order.
+type order struct {
+	*matching.Order
+	// live is false until the order reaches the exchange after the latency.
+	live bool
+}
+
+// action is an order or cancellation taking effect at a given time.
+type action struct {
+	at     time.Time
+	order  *order
+	cancel bool
+}
+
+// broker simulates the exchange for a strategy. It is driven by the engine
+// and is not safe for concurrent use, strategies run in the engine
+// goroutine.
+type broker struct {
+	cfg *Config
+	now time.Time
+
+	// acct holds the balances and the statistics of the run.
+	acct    *matching.Account
+	books   map[string]*cryptomkt.OrderBook
+	orders  []*order
+	pending []*action
+	lastID  int
+}
+
+func newBroker(cfg *Config) *broker {
+	return &broker{
+		cfg:   cfg,
+		acct:  matching.NewAccount(cfg.Balances),
+		books: make(map[string]*cryptomkt.OrderBook),
+	}
+}
+
+func apiError(message string) error {
+	return &cryptomkt.APIError{Status: "error", Message: message}
+}
+
+// CreateOrder implements Trader interface. Funds are locked at once, the
+// order reaches the book after the configured latency.
+func (b *broker) CreateOrder(mor *cryptomkt.MarketOrderRequest) (*cryptomkt.MarketOrderResponse, error) {
+	market := strings.ToUpper(mor.Market)
+	base, quote, err := cryptomkt.SplitMarket(market)
+	if err != nil {
+		return nil, apiError("invalid market")
+	}
+	if mor.Type != "buy" && mor.Type != "sell" {
+		return nil, apiError("invalid type")
+	}
+	if mor.Amount <= 0 || mor.Price <= 0 {
+		return nil, apiError("invalid amount or price")
+	}
+
+	o := &order{Order: &matching.Order{
+		Market:    market,
+		Base:      base,
+		Quote:     quote,
+		Type:      mor.Type,
+		Status:    "active",
+		Price:     int64(mor.Price),
+		Original:  mor.Amount,
+		Remaining: mor.Amount,
+		CreatedAt: b.now.Format(dateLayout),
+	}}
+	if !b.acct.Lock(o.Order, math.Max(b.cfg.MakerFee, b.cfg.TakerFee)) {
+		return nil, apiError("insufficient_funds")
+	}
+
+	b.lastID++
+	o.ID = fmt.Sprintf("B%d", b.lastID)
+	o.UpdatedAt = o.CreatedAt
+	b.orders = append(b.orders, o)
+	b.schedule(&action{at: b.now.Add(b.cfg.Latency), order: o})
+
+	return &cryptomkt.MarketOrderResponse{Status: "success", Data: o.copy()}, nil
+}
+
+// CancelOrder implements Trader interface. The order is cancelled after the
+// configured latency, unless it is filled before.
+func (b *broker) CancelOrder(cor *cryptomkt.CancelOrderRequest) (*cryptomkt.MarketOrderResponse, error) {
+	o := b.find(cor.ID)
+	if o == nil {
+		return nil, apiError("order not found")
+	}
+	if o.Status != "active" {
+		return nil, apiError("order is not active")
+	}
+	b.schedule(&action{at: b.now.Add(b.cfg.Latency), order: o, cancel: true})
+	return &cryptomkt.MarketOrderResponse{Status: "success", Data: o.copy()}, nil
+}
+
+// GetOrderStatus implements Trader interface.
+func (b *broker) GetOrderStatus(opts *cryptomkt.OrderStatusOption) (*cryptomkt.MarketOrderResponse, error) {
+	o := b.find(opts.ID)
+	if o == nil {
+		return nil, apiError("order not found")
+	}
+	return &cryptomkt.MarketOrderResponse{Status: "success", Data: o.copy()}, nil
+}
+
+// GetActiveOrders implements Trader interface.
+func (b *broker) GetActiveOrders(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error) {
+	return b.list(opts.Market, "active"), nil
+}
+
+// GetExecutedOrders implements Trader interface.
+func (b *broker) GetExecutedOrders(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error) {
+	return b.list(opts.Market, "executed"), nil
+}
+
+// GetBalance implements Trader interface.
+func (b *broker) GetBalance() (*cryptomkt.BalanceResponse, error) {
+	wallets := b.acct.Wallets()
+	data := make([]*cryptomkt.Balance, len(wallets))
+	for i, c := range wallets {
+		data[i] = &cryptomkt.Balance{Wallet: c, Available: b.acct.Available[c], Balance: b.acct.Balance(c)}
+	}
+	return &cryptomkt.BalanceResponse{Status: "success", Data: data}, nil
+}
+
+func (b *broker) list(market, status string) *cryptomkt.MarketOrdersResponse {
+	data := make([]*cryptomkt.MarketOrder, 0)
+	for _, o := range b.orders {
+		if o.Status == status && strings.EqualFold(o.Market, market) {
+			data = append(data, o.copy())
+		}
+	}
+	return &cryptomkt.MarketOrdersResponse{Status: "success", Data: data, Pagination: &cryptomkt.Pagination{Limit: len(data)}}
+}
+
+func (b *broker) find(id string) *order {
+	for _, o := range b.orders {
+		if o.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// schedule queues a, keeping the queue sorted by time.
+func (b *broker) schedule(a *action) {
+	i := sort.Search(len(b.pending), func(i int) bool { return b.pending[i].at.After(a.at) })
+	b.pending = append(b.pending, nil)
+	copy(b.pending[i+1:], b.pending[i:])
+	b.pending[i] = a
+}
+
+// runActions applies the pending actions due at or before t.
+func (b *broker) runActions(t time.Time) {
+	for len(b.pending) > 0 && !b.pending[0].at.After(t) {
+		a := b.pending[0]
+		b.pending = b.pending[1:]
+		b.now = a.at
+
+		o := a.order
+		if o.Status != "active" {
+			continue
+		}
+		if a.cancel {
+			b.acct.Cancel(o.Order, b.now.Format(dateLayout))
+			continue
+		}
+		o.live = true
+		b.take(o)
+	}
+}
+
+// book returns the book of market.
+func (b *broker) book(market string) *cryptomkt.OrderBook {
+	ob, ok := b.books[market]
+	if !ok {
+		ob = cryptomkt.NewOrderBook(market)
+		b.books[market] = ob
+	}
+	return ob
+}
+
+// take fills a new live order against the opposite side of the book.
+func (b *broker) take(o *order) {
+	side := "sell"
+	if o.Type == "sell" {
+		side = "buy"
+	}
+	for _, l := range b.book(o.Market).Levels(side) {
+		if o.Status != "active" || !o.Crosses(l.Price) {
+			return
+		}
+		b.acct.Fill(o.Order, math.Min(o.Remaining, l.Amount), l.Price, b.cfg.TakerFee, b.now.Format(dateLayout))
+	}
+}
+
+// onBook applies a snapshot. Resting orders crossed by the new book are
+// filled against it at their own price.
+func (b *broker) onBook(s *BookSnapshot) error {
+	ob := b.book(s.Market)
+	if err := ob.SetSide(s.Side, s.Levels); err != nil {
+		return err
+	}
+	for _, l := range ob.Levels(s.Side) {
+		if _, crossed := b.match(s.Market, s.Side, l.Price, l.Amount); !crossed {
+			break
+		}
+	}
+	return nil
+}
+
+// onTrade fills the resting orders crossed by t at their own price. The
+// taker of t only trades with the orders of the other side; when it is
+// unknown, both sides share the amount of t.
+func (b *broker) onTrade(t *cryptomkt.Trade) error {
+	price, err := strconv.ParseFloat(t.Price, 64)
+	if err != nil {
+		return fmt.Errorf("backtest: invalid trade price %q", t.Price)
+	}
+	amount, err := strconv.ParseFloat(t.Amount, 64)
+	if err != nil {
+		return fmt.Errorf("backtest: invalid trade amount %q", t.Amount)
+	}
+	market := strings.ToUpper(t.Market)
+	switch t.MarketTaker {
+	case "buy", "sell":
+		b.match(market, t.MarketTaker, price, amount)
+	default:
+		left, _ := b.match(market, "buy", price, amount)
+		b.match(market, "sell", price, left)
+	}
+	return nil
+}
+
+// match fills, oldest first, the live orders of market that would trade
+// with amount offered at price by side. It returns the amount left and
+// reports whether any order crossed.
+func (b *broker) match(market, side string, price, amount float64) (left float64, crossed bool) {
+	for _, o := range b.orders {
+		if amount <= 0 {
+			break
+		}
+		if !o.live || o.Status != "active" || o.Market != market || o.Type == side || !o.Crosses(price) {
+			continue
+		}
+		crossed = true
+		qty := math.Min(o.Remaining, amount)
+		b.acct.Fill(o.Order, qty, float64(o.Price), b.cfg.MakerFee, b.now.Format(dateLayout))
+		amount -= qty
+	}
+	return amount, crossed
+}
+
+// copy returns the order as returned by the API.
+func (o *order) copy() *cryptomkt.MarketOrder {
+	return &cryptomkt.MarketOrder{
+		ID:                o.ID,
+		Status:            o.Status,
+		Type:              o.Type,
+		Price:             o.Price,
+		Amount:            &cryptomkt.OrderAmount{Original: o.Original, Remaining: o.Remaining, Executed: o.Executed},
+		ExecutionPrice:    o.ExecutionPrice,
+		AvgExecutionPrice: o.AvgExecutionPrice,
+		Market:            o.Market,
+		CreatedAt:         o.CreatedAt,
+		UpdatedAt:         o.UpdatedAt,
+		ExecutedAt:        o.ExecutedAt,
+	}
+}
@@ -0,0 +1,173 @@
+package cryptomkt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// batchWorkers is the number of requests a batch operation runs at once.
+// The rate limit of the client still applies to each of them.
+const batchWorkers = 4
+
+// OrderResult represents the outcome of a single order of a batch operation.
+type OrderResult struct {
+	// ID of the cancelled order. Empty for created orders that failed.
+	ID string
+	// Order returned by the API, nil when Err is not nil.
+	Order *MarketOrder
+	// Err is the error returned for this order, if any.
+	Err error
+}
+
+// BatchError aggregates the errors of a batch operation.
+type BatchError struct {
+	Errors []error
+}
+
+// Error implements error interface.
+func (err *BatchError) Error() string {
+	msgs := make([]string, len(err.Errors))
+	for i, e := range err.Errors {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("cryptopay: %d operations failed: %s", len(err.Errors), strings.Join(msgs, "; "))
+}
+
+// batchError returns a *BatchError with the errors of results, or nil when
+// every operation succeeded.
+func batchError(results []*OrderResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BatchError{Errors: errs}
+}
+
+// runBatch calls fn for every index in [0, n) using batchWorkers goroutines.
+// Indexes not yet started when ctx is done get ctx.Err() as their error.
+func runBatch(ctx context.Context, n int, fn func(i int) *OrderResult) []*OrderResult {
+	results := make([]*OrderResult, n)
+	runWorkers(ctx, n, func(i int, err error) {
+		if err != nil {
+			results[i] = &OrderResult{Err: err}
+			return
+		}
+		results[i] = fn(i)
+	})
+	return results
+}
+
+// runWorkers calls fn for every index in [0, n) using batchWorkers
+// goroutines. fn gets ctx.Err() for indexes not yet started when ctx is done.
+func runWorkers(ctx context.Context, n int, fn func(i int, err error)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < batchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i, ctx.Err())
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// CancelOrdersFilter selects the active orders cancelled by CancelAllOrders.
+type CancelOrdersFilter struct {
+	// Markets to cancel orders in. Every market is used when empty.
+	Markets []string
+	// Type of the orders to cancel, buy or sell. Both when empty.
+	Type string
+}
+
+// CancelAllOrders cancels every active order matching filter. It walks all
+// the pages of GetActiveOrders before cancelling, so the result contains one
+// entry per order, and returns a *BatchError when any cancel failed. ctx
+// bounds every request, the waits on the rate limiter included.
+func (ps *PrivateService) CancelAllOrders(ctx context.Context, filter *CancelOrdersFilter) ([]*OrderResult, error) {
+	if filter == nil {
+		filter = &CancelOrdersFilter{}
+	}
+
+	markets := filter.Markets
+	if len(markets) == 0 {
+		pub := &PublicService{client: ps.client}
+		mr, err := pub.getMarkets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		markets = mr.Data
+	}
+
+	ids := make([]string, 0)
+	for _, market := range markets {
+		opts := &MarketOrderOptions{Market: market, Limit: 100}
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			mor, err := ps.getActiveOrders(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range mor.Data {
+				if filter.Type == "" || o.Type == filter.Type {
+					ids = append(ids, o.ID)
+				}
+			}
+
+			if mor.Pagination == nil || int(mor.Pagination.Next) <= opts.Page {
+				break
+			}
+			opts.Page = int(mor.Pagination.Next)
+		}
+	}
+
+	results := runBatch(ctx, len(ids), func(i int) *OrderResult {
+		morr, err := ps.cancelOrder(ctx, &CancelOrderRequest{ID: ids[i]})
+		if err != nil {
+			return &OrderResult{ID: ids[i], Err: fmt.Errorf("cryptopay: cancel %s: %w", ids[i], err)}
+		}
+		return &OrderResult{ID: ids[i], Order: morr.Data}
+	})
+	for i, r := range results {
+		r.ID = ids[i]
+	}
+
+	return results, batchError(results)
+}
+
+// CreateOrders creates a batch of orders concurrently. The result keeps the
+// order of mors and a *BatchError is returned when any order failed. ctx
+// bounds every request, the waits on the rate limiter included.
+func (ps *PrivateService) CreateOrders(ctx context.Context, mors []*MarketOrderRequest) ([]*OrderResult, error) {
+	results := runBatch(ctx, len(mors), func(i int) *OrderResult {
+		morr, err := ps.createOrder(ctx, mors[i])
+		if err != nil {
+			return &OrderResult{Err: fmt.Errorf("cryptopay: create %s %s order: %w", mors[i].Market, mors[i].Type, err)}
+		}
+		r := &OrderResult{Order: morr.Data}
+		if morr.Data != nil {
+			r.ID = morr.Data.ID
+		}
+		return r
+	})
+
+	return results, batchError(results)
+}
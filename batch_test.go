@@ -0,0 +1,190 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_CancelAllOrders(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/orders/active":
+			w.Write(getActiveOrdersResponse)
+		case "/v1/orders/cancel":
+			if r.FormValue("id") == "M103967" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(getInvalidOrderResponse)
+				return
+			}
+			w.Write(getCancelOrderResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+			limiter: newRateLimiter(100, time.Second),
+		},
+		Private: true,
+	}
+
+	filter := &CancelOrdersFilter{Markets: []string{"ETHCLP"}}
+	results, err := ps.CancelAllOrders(context.Background(), filter)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Errorf("Expected *BatchError, got %v", err)
+		return
+	}
+	if len(batchErr.Errors) != 1 {
+		t.Errorf("Expected 1 error, got %d", len(batchErr.Errors))
+	}
+
+	expectedLength := 2
+	if len(results) != expectedLength {
+		t.Errorf("Expected %d results, got %d", expectedLength, len(results))
+		return
+	}
+	if results[0].ID != "M103966" || results[0].Err != nil {
+		t.Errorf("Expected M103966 to be cancelled, got %+v", results[0])
+	}
+
+	var apiErr *APIError
+	if results[1].ID != "M103967" || !errors.As(results[1].Err, &apiErr) {
+		t.Errorf("Expected M103967 to fail with *APIError, got %+v", results[1])
+	}
+}
+
+func Test_CreateOrders(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(getCreateOrderResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	mors := []*MarketOrderRequest{
+		{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy"},
+		{Market: "ETHCLP", Amount: 0.2, Price: 9000, Type: "buy"},
+		{Market: "ETHCLP", Amount: 0.1, Price: 8000, Type: "buy"},
+	}
+	results, err := ps.CreateOrders(context.Background(), mors)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	for i, r := range results {
+		if r.Order == nil || r.ID != "M103975" {
+			t.Errorf("Expected order %d to be created, got %+v", i, r)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ps.CreateOrders(ctx, mors); err == nil {
+		t.Errorf("Expected error with cancelled context")
+	}
+}
+
+func Test_CreateOrders_ContextBoundsLimiter(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(getCreateOrderResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			limiter: newRateLimiter(1, time.Hour),
+			private: true,
+		},
+		Private: true,
+	}
+
+	mors := []*MarketOrderRequest{
+		{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy"},
+		{Market: "ETHCLP", Amount: 0.2, Price: 9000, Type: "buy"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results, err := ps.CreateOrders(ctx, mors)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Expected CreateOrders to return with ctx, took %v", d)
+	}
+	if err == nil {
+		t.Fatalf("Expected error with the limiter blocking past ctx")
+	}
+
+	var waited int
+	for _, r := range results {
+		if errors.Is(r.Err, context.DeadlineExceeded) {
+			waited++
+		}
+	}
+	if waited != 1 {
+		t.Errorf("Expected 1 order to fail on ctx, got %d: %v", waited, err)
+	}
+}
+
+func Test_CancelAllOrders_ContextBoundsMarkets(t *testing.T) {
+	requests := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(getMarketsResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	limiter := newRateLimiter(1, time.Hour)
+	limiter.wait(context.Background())
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			limiter: limiter,
+			private: true,
+		},
+		Private: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := ps.CancelAllOrders(ctx, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) || requests != 0 {
+			t.Errorf("Expected the market listing to stop with ctx, got %v and %d requests", err, requests)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected CancelAllOrders to return with ctx")
+	}
+}
+
+var getInvalidOrderResponse = []byte(`
+	{
+		"status": "error",
+		"message": "invalid_order"
+	}
+`)
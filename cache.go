@@ -0,0 +1,257 @@
+package cryptomkt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheBackend stores cached responses. Implementations must be safe for
+// concurrent use. Errors are counted in CacheStats and treated as misses, so
+// a remote store being down never fails a request.
+type CacheBackend interface {
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// MemoryCache is an in-memory CacheBackend. Expired entries are dropped when
+// read.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+// Get implements CacheBackend interface.
+func (mc *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	e, ok := mc.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(mc.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements CacheBackend interface.
+func (mc *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	mc.mu.Lock()
+	mc.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(ttl)}
+	mc.mu.Unlock()
+	return nil
+}
+
+// DefaultCacheTTLs holds the TTL of every public endpoint cached by default.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"/market": time.Hour,
+	"/ticker": 2 * time.Second,
+	"/book":   time.Second,
+	"/trades": 5 * time.Second,
+	"/prices": time.Minute,
+}
+
+// CacheStats represents the counters of a ResponseCache.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Shared counts requests that waited for an identical request in flight
+	// instead of sending their own.
+	Shared uint64
+	// Errors counts failed reads and writes of the backend.
+	Errors uint64
+}
+
+// ResponseCache caches the responses of public endpoints. Only successful
+// responses of endpoints with a TTL are cached, and concurrent identical
+// requests are sent once.
+type ResponseCache struct {
+	hits, misses, shared, errors uint64
+
+	backend CacheBackend
+
+	mu    sync.Mutex
+	ttls  map[string]time.Duration
+	calls map[string]*cacheCall
+}
+
+// cacheLoadTimeout bounds a shared request when the HTTP client has no
+// timeout of its own.
+const cacheLoadTimeout = time.Minute
+
+// cacheCall is a request in flight shared by identical requests. done is
+// closed once body and err are set.
+type cacheCall struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+// NewResponseCache returns a cache using backend with DefaultCacheTTLs. A
+// nil backend uses a MemoryCache.
+func NewResponseCache(backend CacheBackend) *ResponseCache {
+	if backend == nil {
+		backend = NewMemoryCache()
+	}
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for endpoint, ttl := range DefaultCacheTTLs {
+		ttls[endpoint] = ttl
+	}
+	return &ResponseCache{
+		backend: backend,
+		ttls:    ttls,
+		calls:   make(map[string]*cacheCall),
+	}
+}
+
+// SetTTL sets the TTL of endpoint, for example "/ticker". A zero TTL
+// disables the cache for it.
+func (rc *ResponseCache) SetTTL(endpoint string, ttl time.Duration) {
+	rc.mu.Lock()
+	rc.ttls[endpoint] = ttl
+	rc.mu.Unlock()
+}
+
+// Stats returns the counters of the cache.
+func (rc *ResponseCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&rc.hits),
+		Misses: atomic.LoadUint64(&rc.misses),
+		Shared: atomic.LoadUint64(&rc.shared),
+		Errors: atomic.LoadUint64(&rc.errors),
+	}
+}
+
+// ttl returns the TTL of the endpoint of path.
+func (rc *ResponseCache) ttl(path string) time.Duration {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.ttls[path]
+}
+
+// fetch returns the cached body of key, or loads and caches it for ttl. The
+// load is shared by identical requests, so it runs detached from ctx, bounded
+// by timeout; every caller stops waiting for it when its own ctx is done.
+func (rc *ResponseCache) fetch(ctx context.Context, key string, ttl, timeout time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	body, ok, err := rc.backend.Get(ctx, key)
+	if err != nil {
+		atomic.AddUint64(&rc.errors, 1)
+	} else if ok {
+		atomic.AddUint64(&rc.hits, 1)
+		return body, nil
+	}
+
+	rc.mu.Lock()
+	c, ok := rc.calls[key]
+	if ok {
+		rc.mu.Unlock()
+		atomic.AddUint64(&rc.shared, 1)
+	} else {
+		c = &cacheCall{done: make(chan struct{})}
+		rc.calls[key] = c
+		rc.mu.Unlock()
+		atomic.AddUint64(&rc.misses, 1)
+		go rc.load(ctx, key, ttl, timeout, c, load)
+	}
+
+	select {
+	case <-c.done:
+		return c.body, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load runs the shared call c and caches its result.
+func (rc *ResponseCache) load(ctx context.Context, key string, ttl, timeout time.Duration, c *cacheCall, load func(ctx context.Context) ([]byte, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	c.body, c.err = load(ctx)
+	if c.err == nil {
+		if err := rc.backend.Set(ctx, key, c.body, ttl); err != nil {
+			atomic.AddUint64(&rc.errors, 1)
+		}
+	}
+
+	rc.mu.Lock()
+	delete(rc.calls, key)
+	rc.mu.Unlock()
+	close(c.done)
+}
+
+// getCached serves a GET through the cache when the endpoint of path has a
+// TTL. ok is false when the request must be sent uncached.
+func (hc *httpClient) getCached(ctx context.Context, path string, values url.Values) (resp *http.Response, ok bool, err error) {
+	if hc.cache == nil || hc.noCache {
+		return nil, false, nil
+	}
+	ttl := hc.cache.ttl(path)
+	if ttl <= 0 {
+		return nil, false, nil
+	}
+
+	key := "cryptomkt:" + path
+	if values != nil {
+		key += "|" + values.Encode()
+	}
+	timeout := hc.client.Timeout
+	if timeout <= 0 {
+		timeout = cacheLoadTimeout
+	}
+	body, err := hc.cache.fetch(ctx, key, ttl, timeout, func(ctx context.Context) ([]byte, error) {
+		resp, err := hc.getUncached(ctx, path, values)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return ioutil.ReadAll(resp.Body)
+	})
+	if err != nil {
+		return nil, true, err
+	}
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, true, nil
+}
+
+// Uncached returns a PublicService sharing the client of ps whose requests
+// skip the cache.
+func (ps *PublicService) Uncached() *PublicService {
+	hc := &httpClient{
+		client:      ps.client.client,
+		creds:       ps.client.creds,
+		limiter:     ps.client.limiter,
+		noCache:     true,
+		middlewares: ps.client.middlewares,
+		dryRun:      ps.client.dryRun,
+	}
+	return &PublicService{client: hc, Private: ps.Private}
+}
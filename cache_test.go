@@ -0,0 +1,159 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ResponseCache(t *testing.T) {
+	var requests int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write(getMarketsResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rc := NewResponseCache(nil)
+	c := NewPublicClient(WithCache(rc))
+	c.PublicService.client.client = httpCli
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetMarkets(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+	if stats := rc.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %+v", stats)
+	}
+
+	if _, err := c.Uncached().GetMarkets(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected uncached request to reach the server, got %d requests", n)
+	}
+
+	rc.SetTTL("/market", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	c.GetMarkets()
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected cached entry to keep its original TTL, got %d requests", n)
+	}
+}
+
+func Test_ResponseCacheSingleflight(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Write(getTickerResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rc := NewResponseCache(&failingCache{})
+	ps := &PublicService{client: &httpClient{client: httpCli, cache: rc}}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ps.GetTicker("ETHCLP"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for rc.Stats().Shared < callers-1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+	if stats := rc.Stats(); stats.Errors == 0 {
+		t.Errorf("Expected backend errors to be counted, got %+v", stats)
+	}
+}
+
+func Test_ResponseCacheDetachedLoad(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Write(getTickerResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rc := NewResponseCache(nil)
+	hc := &httpClient{client: httpCli, cache: rc}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := hc.getContext(first, "/ticker?market=ETHCLP", nil)
+		firstErr <- err
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	secondErr := make(chan error, 1)
+	go func() {
+		resp, err := hc.getContext(context.Background(), "/ticker?market=ETHCLP", nil)
+		if err == nil {
+			resp.Body.Close()
+		}
+		secondErr <- err
+	}()
+	for rc.Stats().Shared == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-firstErr:
+		if err != context.Canceled {
+			t.Errorf("Expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected cancelled caller to return")
+	}
+
+	close(release)
+	if err := <-secondErr; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+}
+
+// failingCache is a CacheBackend that is always down.
+type failingCache struct{}
+
+func (failingCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("cache down")
+}
+
+func (failingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("cache down")
+}
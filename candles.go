@@ -0,0 +1,221 @@
+package cryptomkt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Timeframe represents the duration of a candle in minutes.
+type Timeframe int
+
+// Timeframes supported by the prices endpoint.
+const (
+	Timeframe1m  Timeframe = 1
+	Timeframe5m  Timeframe = 5
+	Timeframe15m Timeframe = 15
+	Timeframe1h  Timeframe = 60
+	Timeframe4h  Timeframe = 240
+	Timeframe1d  Timeframe = 1440
+	Timeframe1w  Timeframe = 10080
+)
+
+// Valid reports whether the prices endpoint supports tf.
+func (tf Timeframe) Valid() bool {
+	switch tf {
+	case Timeframe1m, Timeframe5m, Timeframe15m, Timeframe1h, Timeframe4h, Timeframe1d, Timeframe1w:
+		return true
+	default:
+		return false
+	}
+}
+
+// Duration returns the duration of a candle of tf.
+func (tf Timeframe) Duration() time.Duration {
+	return time.Duration(tf) * time.Minute
+}
+
+// Candle represents the OHLCV values of a period.
+type Candle struct {
+	// ID de la vela
+	CandleID int `json:"candle_id"`
+	// Precio de apertura
+	OpenPrice float64 `json:"open_price,string"`
+	// Precio más alto
+	HighPrice float64 `json:"hight_price,string"`
+	// Precio de cierre
+	ClosePrice float64 `json:"close_price,string"`
+	// Precio más bajo
+	LowPrice float64 `json:"low_price,string"`
+	// Volumen transado
+	VolumeSum float64 `json:"volume_sum,string"`
+	// Fecha de inicio de la vela
+	CandleDate string `json:"candle_date"`
+	// Cantidad de transacciones
+	TickCount SpecialInt `json:"tick_count"`
+}
+
+// Prices represents the ask and bid candles of a market.
+type Prices struct {
+	Ask []*Candle `json:"ask"`
+	Bid []*Candle `json:"bid"`
+}
+
+// PricesResponse represents a prices response.
+type PricesResponse struct {
+	Status     string      `json:"status"`
+	Data       *Prices     `json:"data"`
+	Pagination *Pagination `json:"pagination"`
+}
+
+// PricesOptions represent query params for prices request.
+type PricesOptions struct {
+	Market    string    `json:"market,omitempty" url:"market"`
+	Timeframe Timeframe `json:"timeframe,omitempty" url:"timeframe"`
+	Page      int       `json:"page,omitempty" url:"page,omitempty"`
+	Limit     int       `json:"limit,omitempty" url:"limit,omitempty"`
+}
+
+// GetPrices returns the ask and bid candles of a market.
+func (ps *PublicService) GetPrices(opts *PricesOptions) (*PricesResponse, error) {
+	if !opts.Timeframe.Valid() {
+		return nil, fmt.Errorf("cryptopay: unsupported timeframe %d", opts.Timeframe)
+	}
+
+	v, err := query.Values(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ps.client.get(fmt.Sprintf("/prices?%s", v.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PricesResponse
+	if err := unmarshalJSON(resp.Body, &pr); err != nil {
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// CandleAggregator builds candles of any interval from trades, for the
+// intervals the prices endpoint does not offer.
+type CandleAggregator struct {
+	interval time.Duration
+	candles  map[int64]*Candle
+	// last holds the timestamp of the trade that set each close price.
+	last map[int64]time.Time
+	// first holds the timestamp of the trade that set each open price.
+	first map[int64]time.Time
+}
+
+// NewCandleAggregator returns an aggregator of candles of interval, which
+// must be positive.
+func NewCandleAggregator(interval time.Duration) (*CandleAggregator, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("cryptopay: invalid candle interval %v", interval)
+	}
+	return &CandleAggregator{
+		interval: interval,
+		candles:  make(map[int64]*Candle),
+		last:     make(map[int64]time.Time),
+		first:    make(map[int64]time.Time),
+	}, nil
+}
+
+// Add adds a trade to its candle. Trades may be added in any order.
+func (ca *CandleAggregator) Add(t *Trade) error {
+	ts, err := time.Parse(dateLayout, t.Timestamp)
+	if err != nil {
+		return fmt.Errorf("cryptopay: invalid trade timestamp %q", t.Timestamp)
+	}
+	price, err := strconv.ParseFloat(t.Price, 64)
+	if err != nil {
+		return fmt.Errorf("cryptopay: invalid trade price %q", t.Price)
+	}
+	amount, err := strconv.ParseFloat(t.Amount, 64)
+	if err != nil {
+		return fmt.Errorf("cryptopay: invalid trade amount %q", t.Amount)
+	}
+
+	start := ts.Truncate(ca.interval)
+	id := start.UnixNano() / int64(ca.interval)
+	c, ok := ca.candles[id]
+	if !ok {
+		c = &Candle{
+			CandleID:   int(id),
+			OpenPrice:  price,
+			HighPrice:  price,
+			ClosePrice: price,
+			LowPrice:   price,
+			CandleDate: start.Format(dateLayout),
+		}
+		ca.candles[id] = c
+		ca.first[id], ca.last[id] = ts, ts
+	}
+
+	if price > c.HighPrice {
+		c.HighPrice = price
+	}
+	if price < c.LowPrice {
+		c.LowPrice = price
+	}
+	if ts.Before(ca.first[id]) {
+		c.OpenPrice, ca.first[id] = price, ts
+	}
+	if !ts.Before(ca.last[id]) {
+		c.ClosePrice, ca.last[id] = price, ts
+	}
+	c.VolumeSum += amount
+	c.TickCount++
+
+	return nil
+}
+
+// Candles returns the candles built so far in chronological order.
+func (ca *CandleAggregator) Candles() []*Candle {
+	ids := make([]int64, 0, len(ca.candles))
+	for id := range ca.candles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	candles := make([]*Candle, len(ids))
+	for i, id := range ids {
+		candles[i] = ca.candles[id]
+	}
+	return candles
+}
+
+// GetTradeCandles walks every page of GetTrades and aggregates the trades in
+// candles of interval.
+func (ps *PublicService) GetTradeCandles(opts *TradesOptions, interval time.Duration) ([]*Candle, error) {
+	ca, err := NewCandleAggregator(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	page := *opts
+	for {
+		tr, err := ps.GetTrades(&page)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tr.Data {
+			if err := ca.Add(t); err != nil {
+				return nil, err
+			}
+		}
+		if tr.Pagination == nil || int(tr.Pagination.Next) <= page.Page {
+			break
+		}
+		page.Page = int(tr.Pagination.Next)
+	}
+
+	return ca.Candles(), nil
+}
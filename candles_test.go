@@ -0,0 +1,180 @@
+package cryptomkt
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_GetPrices(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/prices" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		if tf := r.URL.Query().Get("timeframe"); tf != "60" {
+			t.Errorf("Expected timeframe 60, got %s", tf)
+		}
+		w.Write(getPricesResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	pr, err := ps.GetPrices(&PricesOptions{Market: "ETHCLP", Timeframe: Timeframe1h})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	if len(pr.Data.Ask) != 1 || len(pr.Data.Bid) != 1 {
+		t.Errorf("Expected 1 ask and 1 bid candle, got %d and %d", len(pr.Data.Ask), len(pr.Data.Bid))
+		return
+	}
+	ask := pr.Data.Ask[0]
+	if ask.HighPrice != 575000 || ask.VolumeSum != 2.5 || ask.TickCount != 12 {
+		t.Errorf("Expected high 575000, volume 2.5 and 12 ticks, got %+v", ask)
+	}
+
+	if _, err := ps.GetPrices(&PricesOptions{Market: "ETHCLP", Timeframe: 30}); err == nil {
+		t.Errorf("Expected error for unsupported timeframe")
+	}
+}
+
+func Test_CandleAggregator(t *testing.T) {
+	ca, err := NewCandleAggregator(2 * time.Minute)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	trades := []*Trade{
+		{Price: "10100", Amount: "1", Timestamp: "2017-09-01T14:01:30.000000"},
+		{Price: "10000", Amount: "0.5", Timestamp: "2017-09-01T14:00:10.000000"},
+		{Price: "10200", Amount: "0.25", Timestamp: "2017-09-01T14:00:50.5"},
+		{Price: "9900", Amount: "2", Timestamp: "2017-09-01T14:02:00"},
+	}
+	for _, tr := range trades {
+		if err := ca.Add(tr); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+	}
+
+	candles := ca.Candles()
+	if len(candles) != 2 {
+		t.Errorf("Expected 2 candles, got %d", len(candles))
+		return
+	}
+
+	c := candles[0]
+	if c.CandleDate != "2017-09-01T14:00:00" {
+		t.Errorf("Expected candle date 2017-09-01T14:00:00, got %s", c.CandleDate)
+	}
+	if c.OpenPrice != 10000 || c.HighPrice != 10200 || c.LowPrice != 10000 || c.ClosePrice != 10100 {
+		t.Errorf("Expected OHLC 10000/10200/10000/10100, got %+v", c)
+	}
+	if c.VolumeSum != 1.75 || c.TickCount != 3 {
+		t.Errorf("Expected volume 1.75 and 3 ticks, got %v and %d", c.VolumeSum, c.TickCount)
+	}
+	if candles[1].OpenPrice != 9900 || candles[1].TickCount != 1 {
+		t.Errorf("Expected second candle to open at 9900, got %+v", candles[1])
+	}
+
+	if err := ca.Add(&Trade{Price: "1", Amount: "1", Timestamp: "yesterday"}); err == nil {
+		t.Errorf("Expected error for invalid timestamp")
+	}
+}
+
+func Test_CandleAggregator_SubSecond(t *testing.T) {
+	ca, err := NewCandleAggregator(500 * time.Millisecond)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	trades := []*Trade{
+		{Price: "10000", Amount: "1", Timestamp: "2017-09-01T14:00:00.1"},
+		{Price: "10100", Amount: "1", Timestamp: "2017-09-01T14:00:00.6"},
+		{Price: "10200", Amount: "1", Timestamp: "2017-09-01T14:00:00.7"},
+	}
+	for _, tr := range trades {
+		if err := ca.Add(tr); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+	}
+
+	candles := ca.Candles()
+	if len(candles) != 2 {
+		t.Errorf("Expected 2 candles, got %d", len(candles))
+		return
+	}
+	if candles[1].CandleID != candles[0].CandleID+1 || candles[1].TickCount != 2 {
+		t.Errorf("Expected consecutive candles, got %+v and %+v", candles[0], candles[1])
+	}
+
+	if _, err := NewCandleAggregator(0); err == nil {
+		t.Errorf("Expected error for zero interval")
+	}
+}
+
+func Test_GetTradeCandles_SubSecond(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "success", "data": [
+			{"price": "10000", "amount": "1", "tid": "1", "timestamp": "2017-09-01T14:00:00.1", "market": "ETHCLP"},
+			{"price": "10100", "amount": "1", "tid": "2", "timestamp": "2017-09-01T14:00:00.3", "market": "ETHCLP"}
+		]}`))
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	candles, err := ps.GetTradeCandles(&TradesOptions{Market: "ETHCLP"}, 250*time.Millisecond)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(candles) != 2 || candles[1].CandleDate != "2017-09-01T14:00:00.25" {
+		t.Errorf("Expected 2 candles, the second at 14:00:00.25, got %+v", candles)
+	}
+
+	if _, err := ps.GetTradeCandles(&TradesOptions{Market: "ETHCLP"}, 0); err == nil {
+		t.Errorf("Expected error for zero interval")
+	}
+}
+
+var getPricesResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {
+			"previous": "null",
+			"limit": 20,
+			"page": 0,
+			"next": "null"
+		},
+		"data": {
+			"ask": [
+				{
+					"candle_id": 409016,
+					"open_price": "570000",
+					"hight_price": "575000",
+					"close_price": "572000",
+					"low_price": "569000",
+					"volume_sum": "2.5",
+					"candle_date": "2017-09-01T14:00:00",
+					"tick_count": "12"
+				}
+			],
+			"bid": [
+				{
+					"candle_id": 409017,
+					"open_price": "565000",
+					"hight_price": "568000",
+					"close_price": "566000",
+					"low_price": "560000",
+					"volume_sum": "1.75",
+					"candle_date": "2017-09-01T14:00:00",
+					"tick_count": "7"
+				}
+			]
+		}
+	}
+`)
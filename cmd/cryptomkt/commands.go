@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// action runs a command with its positional arguments.
+type action func(c *cryptomkt.Client, args []string) (*result, error)
+
+// command represents a subcommand of the tool.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	// private commands need credentials.
+	private bool
+	// flags registers the flags of the command and returns its action.
+	flags func(fs *flag.FlagSet) action
+}
+
+var commands = []*command{
+	{
+		name:    "markets",
+		summary: "list the available markets",
+		flags: func(fs *flag.FlagSet) action {
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				mr, err := c.GetMarkets()
+				if err != nil {
+					return nil, err
+				}
+				r := &result{value: mr.Data, headers: []string{"market"}}
+				for _, m := range mr.Data {
+					r.addRow(m)
+				}
+				return r, nil
+			}
+		},
+	},
+	{
+		name:    "ticker",
+		usage:   "[market ...]",
+		summary: "show the ticker of the given markets, or of every market",
+		flags: func(fs *flag.FlagSet) action {
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				tickers, err := c.GetTickers(context.Background(), args...)
+				if tickers == nil {
+					return nil, err
+				}
+				markets := make([]string, 0, len(tickers))
+				values := make(map[string]*cryptomkt.Ticker, len(tickers))
+				for market, tr := range tickers {
+					if tr.Err == nil {
+						markets = append(markets, market)
+						values[market] = tr.Ticker
+					}
+				}
+				sort.Strings(markets)
+
+				r := &result{value: values, headers: []string{"market", "bid", "ask", "last_price", "low", "high", "volume", "timestamp"}}
+				for _, m := range markets {
+					t := values[m]
+					r.addRow(m, t.Bid, t.Ask, t.LastPrice, t.Low, t.High, t.Volume, t.Timestamp)
+				}
+				return r, err
+			}
+		},
+	},
+	{
+		name:    "book",
+		summary: "show a page of the order book of a market",
+		flags: func(fs *flag.FlagSet) action {
+			opts := &cryptomkt.BooksOptions{}
+			fs.StringVar(&opts.Market, "market", "", "market, for example ETHCLP (required)")
+			fs.StringVar(&opts.Type, "type", "buy", "side of the book, buy or sell")
+			fs.IntVar(&opts.Page, "page", 0, "page")
+			fs.IntVar(&opts.Limit, "limit", 0, "page size")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "market"); err != nil {
+					return nil, err
+				}
+				br, err := c.GetOrdersBook(opts)
+				if err != nil {
+					return nil, err
+				}
+				r := &result{value: br.Data, headers: []string{"price", "amount", "timestamp"}}
+				for _, b := range br.Data {
+					r.addRow(b.Price, b.Amount, b.Timestamp)
+				}
+				return r, nil
+			}
+		},
+	},
+	{
+		name:    "trades",
+		summary: "list the trades of a market",
+		flags: func(fs *flag.FlagSet) action {
+			opts := &cryptomkt.TradesOptions{}
+			fs.StringVar(&opts.Market, "market", "", "market, for example ETHCLP (required)")
+			fs.StringVar(&opts.StartDate, "start", "", "start date, YYYY-MM-DD")
+			fs.StringVar(&opts.EndDate, "end", "", "end date, YYYY-MM-DD")
+			fs.IntVar(&opts.Page, "page", 0, "page")
+			fs.IntVar(&opts.Limit, "limit", 0, "page size")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "market"); err != nil {
+					return nil, err
+				}
+				tr, err := c.GetTrades(opts)
+				if err != nil {
+					return nil, err
+				}
+				r := &result{value: tr.Data, headers: []string{"tid", "timestamp", "market_taker", "price", "amount"}}
+				for _, t := range tr.Data {
+					r.addRow(t.Tid, t.Timestamp, t.MarketTaker, t.Price, t.Amount)
+				}
+				return r, nil
+			}
+		},
+	},
+	{
+		name:    "orders active",
+		summary: "list the active orders of a market",
+		private: true,
+		flags:   listOrders((*cryptomkt.PrivateService).GetActiveOrders),
+	},
+	{
+		name:    "orders executed",
+		summary: "list the executed orders of a market",
+		private: true,
+		flags:   listOrders((*cryptomkt.PrivateService).GetExecutedOrders),
+	},
+	{
+		name:    "orders status",
+		summary: "show an order",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			opts := &cryptomkt.OrderStatusOption{}
+			fs.StringVar(&opts.ID, "id", "", "order ID (required)")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "id"); err != nil {
+					return nil, err
+				}
+				return orderResult(c.GetOrderStatus(opts))
+			}
+		},
+	},
+	{
+		name:    "orders create",
+		summary: "create a limit order",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			mor := &cryptomkt.MarketOrderRequest{}
+			fs.StringVar(&mor.Market, "market", "", "market, for example ETHCLP (required)")
+			fs.StringVar(&mor.Type, "type", "", "buy or sell (required)")
+			fs.Float64Var(&mor.Amount, "amount", 0, "amount to buy or sell (required)")
+			fs.IntVar(&mor.Price, "price", 0, "limit price (required)")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "market", "type", "amount", "price"); err != nil {
+					return nil, err
+				}
+				return orderResult(c.CreateOrder(mor))
+			}
+		},
+	},
+	{
+		name:    "orders cancel",
+		summary: "cancel an order",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			cor := &cryptomkt.CancelOrderRequest{}
+			fs.StringVar(&cor.ID, "id", "", "order ID (required)")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "id"); err != nil {
+					return nil, err
+				}
+				return orderResult(c.CancelOrder(cor))
+			}
+		},
+	},
+	{
+		name:    "balance",
+		summary: "show the balance of every wallet",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				br, err := c.GetBalance()
+				if err != nil {
+					return nil, err
+				}
+				r := &result{value: br.Data, headers: []string{"wallet", "available", "balance"}}
+				for _, b := range br.Data {
+					r.addRow(b.Wallet, b.Available, b.Balance)
+				}
+				return r, nil
+			}
+		},
+	},
+	{
+		name:    "payment create",
+		summary: "create a payment order",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			p := &cryptomkt.PaymentRequest{}
+			fs.Int64Var(&p.Amount, "amount", 0, "amount to receive (required)")
+			fs.StringVar(&p.Currency, "currency", "", "currency to receive (required)")
+			fs.StringVar(&p.Receiver, "receiver", "", "email of the receiver (required)")
+			fs.StringVar(&p.ExternalID, "external-id", "", "external ID")
+			fs.StringVar(&p.NotificationURL, "callback-url", "", "notification URL")
+			fs.StringVar(&p.ErrorURL, "error-url", "", "error URL")
+			fs.StringVar(&p.SuccessURL, "success-url", "", "success URL")
+			fs.StringVar(&p.RefundEmail, "refund-email", "", "refund email")
+			fs.StringVar(&p.Language, "language", "", "language, es, en or pt")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "amount", "currency", "receiver"); err != nil {
+					return nil, err
+				}
+				return paymentResult(c.CreatePayment(p))
+			}
+		},
+	},
+	{
+		name:    "payment status",
+		summary: "show a payment order",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			id := fs.String("id", "", "payment order ID (required)")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				if err := required(fs, "id"); err != nil {
+					return nil, err
+				}
+				return paymentResult(c.PaymentStatus(*id))
+			}
+		},
+	},
+	{
+		name:    "payment list",
+		summary: "list the payment orders",
+		private: true,
+		flags: func(fs *flag.FlagSet) action {
+			opts := &cryptomkt.PaymentOrdersOptions{}
+			fs.StringVar(&opts.StartDate, "start", "", "start date")
+			fs.StringVar(&opts.EndDate, "end", "", "end date")
+			fs.IntVar(&opts.Page, "page", 0, "page")
+			fs.IntVar(&opts.Limit, "limit", 0, "page size")
+			return func(c *cryptomkt.Client, args []string) (*result, error) {
+				por, err := c.PaymentOrders(opts)
+				if err != nil {
+					return nil, err
+				}
+				r := &result{value: por.Data, headers: paymentHeaders}
+				for _, p := range por.Data {
+					addPaymentRow(r, p)
+				}
+				return r, nil
+			}
+		},
+	},
+}
+
+// findCommand returns the command named by the first one or two arguments
+// and the remaining arguments.
+func findCommand(args []string) (*command, []string) {
+	if len(args) >= 2 {
+		for _, cmd := range commands {
+			if cmd.name == args[0]+" "+args[1] {
+				return cmd, args[2:]
+			}
+		}
+	}
+	if len(args) >= 1 {
+		for _, cmd := range commands {
+			if cmd.name == args[0] {
+				return cmd, args[1:]
+			}
+		}
+	}
+	return nil, args
+}
+
+// required returns an error naming the first flag of names not set.
+func required(fs *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range names {
+		if !set[name] {
+			return fmt.Errorf("flag -%s is required", name)
+		}
+	}
+	return nil
+}
+
+var orderHeaders = []string{"id", "market", "type", "status", "price", "original", "remaining", "executed", "created_at"}
+
+func addOrderRow(r *result, o *cryptomkt.MarketOrder) {
+	amount := o.Amount
+	if amount == nil {
+		amount = &cryptomkt.OrderAmount{}
+	}
+	r.addRow(o.ID, o.Market, o.Type, o.Status, o.Price, amount.Original, amount.Remaining, amount.Executed, o.CreatedAt)
+}
+
+// listOrders returns the flags of a command listing orders with list.
+func listOrders(list func(*cryptomkt.PrivateService, *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error)) func(fs *flag.FlagSet) action {
+	return func(fs *flag.FlagSet) action {
+		opts := &cryptomkt.MarketOrderOptions{}
+		fs.StringVar(&opts.Market, "market", "", "market, for example ETHCLP (required)")
+		fs.IntVar(&opts.Page, "page", 0, "page")
+		fs.IntVar(&opts.Limit, "limit", 0, "page size")
+		return func(c *cryptomkt.Client, args []string) (*result, error) {
+			if err := required(fs, "market"); err != nil {
+				return nil, err
+			}
+			mor, err := list(&c.PrivateService, opts)
+			if err != nil {
+				return nil, err
+			}
+			r := &result{value: mor.Data, headers: orderHeaders}
+			for _, o := range mor.Data {
+				addOrderRow(r, o)
+			}
+			return r, nil
+		}
+	}
+}
+
+func orderResult(morr *cryptomkt.MarketOrderResponse, err error) (*result, error) {
+	if err != nil {
+		return nil, err
+	}
+	r := &result{value: morr.Data, headers: orderHeaders}
+	if morr.Data != nil {
+		addOrderRow(r, morr.Data)
+	}
+	return r, nil
+}
+
+var paymentHeaders = []string{"id", "external_id", "status", "to_receive", "currency", "deposit_address", "payment_url", "created_at"}
+
+func addPaymentRow(r *result, p *cryptomkt.PaymentResponse) {
+	r.addRow(p.ID, p.ExternalID, p.Status, p.ToReceive, p.ToReceiveCurrency, p.DepositAddress, p.PaymentURL, p.CreatedAt)
+}
+
+func paymentResult(p *cryptomkt.PaymentResponse, err error) (*result, error) {
+	if err != nil {
+		return nil, err
+	}
+	r := &result{value: p, headers: paymentHeaders}
+	if p != nil {
+		addPaymentRow(r, p)
+	}
+	return r, nil
+}
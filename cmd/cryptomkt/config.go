@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Environment variables holding the credentials. They take precedence over
+// the config file.
+const (
+	envAPIKey    = "CRYPTOMKT_API_KEY"
+	envAPISecret = "CRYPTOMKT_API_SECRET"
+	envConfig    = "CRYPTOMKT_CONFIG"
+)
+
+// config represents the config file, a JSON object.
+type config struct {
+	APIKey    string `json:"api_key"`
+	APISecret string `json:"api_secret"`
+}
+
+// defaultConfigPath returns the path of the config file used when none is
+// given: $CRYPTOMKT_CONFIG or ~/.cryptomkt.json.
+func defaultConfigPath(getenv func(string) string) string {
+	if path := getenv(envConfig); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cryptomkt.json")
+}
+
+// loadConfig reads the config file at path, if any, and applies the
+// environment on top of it. A missing file is only an error when required.
+func loadConfig(path string, required bool, getenv func(string) string) (*config, error) {
+	cfg := &config{}
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, cfg); err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+			}
+		case !os.IsNotExist(err) || required:
+			return nil, err
+		}
+	}
+
+	if key := getenv(envAPIKey); key != "" {
+		cfg.APIKey = key
+	}
+	if secret := getenv(envAPISecret); secret != "" {
+		cfg.APISecret = secret
+	}
+	return cfg, nil
+}
@@ -0,0 +1,120 @@
+// Command cryptomkt inspects CryptoMarket and operates an account from the
+// command line.
+//
+// Usage:
+//
+//	cryptomkt [-o table|json|csv] [-config file] [-dry-run] <command> [flags] [args]
+//
+// Credentials are read from CRYPTOMKT_API_KEY and CRYPTOMKT_API_SECRET, or
+// from a JSON config file with "api_key" and "api_secret" keys, by default
+// ~/.cryptomkt.json. With -dry-run the requests are signed and printed
+// instead of sent.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// transport sends the requests of the tool. The default transport is used
+// when nil.
+var transport http.RoundTripper
+
+// globals holds the flags accepted before and after the command name.
+type globals struct {
+	output string
+	config string
+	dryRun bool
+}
+
+// register adds the flags to fs, using the current values as defaults.
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.output, "o", g.output, "output format: table, json or csv")
+	fs.StringVar(&g.config, "config", g.config, "config file with the credentials")
+	fs.BoolVar(&g.dryRun, "dry-run", g.dryRun, "print the requests instead of sending them")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run runs the tool and returns its exit code.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	defaultConfig := defaultConfigPath(getenv)
+	g := &globals{output: formatTable, config: defaultConfig}
+
+	top := flag.NewFlagSet("cryptomkt", flag.ContinueOnError)
+	top.SetOutput(stderr)
+	g.register(top)
+	top.Usage = func() { usage(stderr) }
+	if err := top.Parse(args); err != nil {
+		return 2
+	}
+
+	cmd, rest := findCommand(top.Args())
+	if cmd == nil {
+		usage(stderr)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("cryptomkt "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	g.register(fs)
+	act := cmd.flags(fs)
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+
+	opts := []cryptomkt.Option{cryptomkt.WithHTTPClient(&http.Client{Transport: transport})}
+	if g.dryRun {
+		opts = append(opts, cryptomkt.WithDryRun(stdout))
+	}
+
+	var c *cryptomkt.Client
+	if cmd.private {
+		cfg, err := loadConfig(g.config, g.config != defaultConfig, getenv)
+		if err != nil {
+			fmt.Fprintf(stderr, "cryptomkt: %v\n", err)
+			return 1
+		}
+		if cfg.APIKey == "" || cfg.APISecret == "" {
+			fmt.Fprintf(stderr, "cryptomkt: missing credentials, set %s and %s or use a config file\n", envAPIKey, envAPISecret)
+			return 1
+		}
+		c = cryptomkt.NewClient(cfg.APIKey, cfg.APISecret, opts...)
+	} else {
+		c = cryptomkt.NewPublicClient(opts...)
+	}
+
+	r, err := act(c, fs.Args())
+	if errors.Is(err, cryptomkt.ErrDryRun) {
+		return 0
+	}
+	if r != nil {
+		if perr := r.print(stdout, g.output); perr != nil {
+			fmt.Fprintf(stderr, "cryptomkt: %v\n", perr)
+			return 1
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cryptomkt: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cryptomkt [-o table|json|csv] [-config file] [-dry-run] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", strings.TrimSpace(cmd.name+" "+cmd.usage), cmd.summary)
+	}
+}
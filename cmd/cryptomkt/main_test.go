@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func Test_LoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"api_key": "file-key", "api_secret": "file-secret"}`), 0600); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	cfg, err := loadConfig(path, true, env(map[string]string{envAPIKey: "env-key"}))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if cfg.APIKey != "env-key" || cfg.APISecret != "file-secret" {
+		t.Errorf("Expected env key and file secret, got %+v", cfg)
+	}
+
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.json"), true, env(nil)); err == nil {
+		t.Errorf("Expected error for missing config file")
+	}
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.json"), false, env(nil)); err != nil {
+		t.Errorf("Unexpected error for missing default config file: %v", err)
+	}
+}
+
+func Test_RunDryRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	vars := map[string]string{envConfig: "none", envAPIKey: "some-key", envAPISecret: "some-secret"}
+	args := []string{"-dry-run", "orders", "create", "-market", "ETHCLP", "-type", "buy", "-amount", "0.3", "-price", "10000"}
+
+	if code := run(args, &stdout, &stderr, env(vars)); code != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", code, stderr.String())
+		return
+	}
+
+	out := stdout.String()
+	for _, expected := range []string{"POST /v1/orders HTTP/1.1", "amount=0.3", "X-Mkt-Apikey: some-key", "X-Mkt-Signature: "} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in dry run output, got\n%s", expected, out)
+		}
+	}
+}
+
+func Test_RunMissingCredentials(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"balance"}, &stdout, &stderr, env(map[string]string{envConfig: "none"})); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "missing credentials") {
+		t.Errorf("Expected missing credentials error, got %s", stderr.String())
+	}
+}
+
+func Test_RunOutput(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "success", "data": ["ETHCLP", "BTCCLP"]}`))
+	}))
+	defer s.Close()
+	transport = &http.Transport{
+		DialContext: func(_ context.Context, network, _ string) (net.Conn, error) {
+			return net.Dial(network, s.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	defer func() { transport = nil }()
+
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{formatCSV, "market\nETHCLP\nBTCCLP\n"},
+		{formatJSON, "[\n  \"ETHCLP\",\n  \"BTCCLP\"\n]\n"},
+		{formatTable, "MARKET\nETHCLP\nBTCCLP\n"},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-o", c.format, "markets"}, &stdout, &stderr, env(nil)); code != 0 {
+			t.Errorf("Expected exit code 0, got %d: %s", code, stderr.String())
+			continue
+		}
+		if stdout.String() != c.expected {
+			t.Errorf("Expected %s output %q, got %q", c.format, c.expected, stdout.String())
+		}
+	}
+}
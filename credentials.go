@@ -0,0 +1,331 @@
+package cryptomkt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Environment variables read by NewEnvProvider by default.
+const (
+	EnvAPIKey    = "CRYPTOMKT_API_KEY"
+	EnvAPISecret = "CRYPTOMKT_API_SECRET"
+)
+
+// errCredentialsClosed is returned by providers after Close.
+var errCredentialsClosed = errors.New("cryptopay: credentials provider is closed")
+
+// Secret is an API secret. It is always formatted as [REDACTED], so it does
+// not leak through fmt or logs.
+type Secret []byte
+
+const redacted = "[REDACTED]"
+
+// String implements fmt.Stringer interface.
+func (s Secret) String() string { return redacted }
+
+// GoString implements fmt.GoStringer interface.
+func (s Secret) GoString() string { return redacted }
+
+// Format implements fmt.Formatter interface.
+func (s Secret) Format(f fmt.State, verb rune) { io.WriteString(f, redacted) }
+
+// Zero overwrites the secret with zeros.
+func (s Secret) Zero() {
+	for i := range s {
+		s[i] = 0
+	}
+}
+
+// Credentials represents an API key and its secret.
+type Credentials struct {
+	Key    string
+	Secret Secret
+}
+
+// copy returns credentials with a copy of the secret.
+func (c *Credentials) copy() *Credentials {
+	return &Credentials{Key: c.Key, Secret: append(Secret(nil), c.Secret...)}
+}
+
+// CredentialsProvider returns the credentials private requests are signed
+// with. It is consulted before every private request, so the credentials
+// may be rotated at any time. The returned credentials belong to the
+// caller, which zeroes the secret once the request is signed. Providers
+// keeping secrets in memory implement io.Closer to zero them.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+// WithCredentials signs the private requests of the client with the
+// credentials of p instead of the key and secret given to NewClient.
+func WithCredentials(p CredentialsProvider) Option {
+	return func(c *Client) {
+		if c.accountOnly("WithCredentials") {
+			return
+		}
+		for _, hc := range c.httpClients() {
+			hc.creds = p
+		}
+	}
+}
+
+// Close zeroes the secrets held by the credentials providers of the
+// client. Private requests fail afterwards.
+func (c *Client) Close() error {
+	var closed []CredentialsProvider
+	var err error
+	for _, hc := range c.httpClients() {
+		closer, ok := hc.creds.(io.Closer)
+		if !ok {
+			continue
+		}
+		seen := false
+		for _, p := range closed {
+			seen = seen || p == hc.creds
+		}
+		if seen {
+			continue
+		}
+		closed = append(closed, hc.creds)
+		if cerr := closer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// StaticProvider provides credentials held in memory.
+type StaticProvider struct {
+	mu    sync.RWMutex
+	creds *Credentials
+}
+
+var _ CredentialsProvider = (*StaticProvider)(nil)
+
+// NewStaticProvider returns a provider of key and secret.
+func NewStaticProvider(key, secret string) *StaticProvider {
+	return &StaticProvider{creds: &Credentials{Key: key, Secret: Secret(secret)}}
+}
+
+// Credentials implements CredentialsProvider interface.
+func (p *StaticProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.creds == nil {
+		return nil, errCredentialsClosed
+	}
+	return p.creds.copy(), nil
+}
+
+// Rotate replaces the credentials. Requests already signed keep the
+// previous ones.
+func (p *StaticProvider) Rotate(key, secret string) {
+	p.mu.Lock()
+	if p.creds != nil {
+		p.creds.Secret.Zero()
+	}
+	p.creds = &Credentials{Key: key, Secret: Secret(secret)}
+	p.mu.Unlock()
+}
+
+// Close zeroes the secret.
+func (p *StaticProvider) Close() error {
+	p.mu.Lock()
+	if p.creds != nil {
+		p.creds.Secret.Zero()
+		p.creds = nil
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+// EnvProvider provides credentials read from environment variables on
+// every request.
+type EnvProvider struct {
+	keyVar    string
+	secretVar string
+}
+
+var _ CredentialsProvider = (*EnvProvider)(nil)
+
+// NewEnvProvider returns a provider reading the key and the secret from the
+// keyVar and secretVar environment variables, by default EnvAPIKey and
+// EnvAPISecret.
+func NewEnvProvider(keyVar, secretVar string) *EnvProvider {
+	if keyVar == "" {
+		keyVar = EnvAPIKey
+	}
+	if secretVar == "" {
+		secretVar = EnvAPISecret
+	}
+	return &EnvProvider{keyVar: keyVar, secretVar: secretVar}
+}
+
+// Credentials implements CredentialsProvider interface.
+func (p *EnvProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	key, secret := os.Getenv(p.keyVar), os.Getenv(p.secretVar)
+	if key == "" || secret == "" {
+		return nil, fmt.Errorf("cryptopay: $%s and $%s must be set", p.keyVar, p.secretVar)
+	}
+	return &Credentials{Key: key, Secret: Secret(secret)}, nil
+}
+
+// FileProvider provides credentials read from a JSON file with api_key and
+// api_secret fields. The file is read again when it changes, and must not be
+// accessible by other users.
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	creds   *Credentials
+	modTime time.Time
+	size    int64
+	closed  bool
+}
+
+var _ CredentialsProvider = (*FileProvider)(nil)
+
+// NewFileProvider returns a provider of the credentials in the file at
+// path.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Credentials implements CredentialsProvider interface.
+func (p *FileProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errCredentialsClosed
+	}
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("cryptopay: credentials file %s is accessible by other users (mode %v)", p.path, info.Mode().Perm())
+	}
+	if p.creds == nil || !info.ModTime().Equal(p.modTime) || info.Size() != p.size {
+		b, err := ioutil.ReadFile(p.path)
+		if err != nil {
+			return nil, err
+		}
+		creds, err := parseCredentials(b)
+		Secret(b).Zero()
+		if err != nil {
+			return nil, fmt.Errorf("cryptopay: invalid credentials file %s: %v", p.path, err)
+		}
+		if p.creds != nil {
+			p.creds.Secret.Zero()
+		}
+		p.creds, p.modTime, p.size = creds, info.ModTime(), info.Size()
+	}
+	return p.creds.copy(), nil
+}
+
+// Close zeroes the secret read from the file.
+func (p *FileProvider) Close() error {
+	p.mu.Lock()
+	if p.creds != nil {
+		p.creds.Secret.Zero()
+		p.creds = nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+	return nil
+}
+
+// parseCredentials parses a JSON object with api_key and api_secret fields.
+// The secret is copied from b without going through a string when it has no
+// escapes.
+func parseCredentials(b []byte) (*Credentials, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	var key string
+	if err := json.Unmarshal(fields["api_key"], &key); err != nil || key == "" {
+		return nil, errors.New("api_key is required")
+	}
+	raw := fields["api_secret"]
+	if len(raw) < 3 || raw[0] != '"' || raw[len(raw)-1] != '"' || bytes.IndexByte(raw, '\\') >= 0 {
+		return nil, errors.New("api_secret is required")
+	}
+	return &Credentials{Key: key, Secret: append(Secret(nil), raw[1:len(raw)-1]...)}, nil
+}
+
+// SecretStore reads secrets from a secret manager such as Vault.
+type SecretStore interface {
+	// ReadSecret returns the fields of the secret at path.
+	ReadSecret(ctx context.Context, path string) (map[string][]byte, error)
+}
+
+// VaultProvider provides credentials read from a SecretStore, stored at a
+// path with api_key and api_secret fields. They are read again once their
+// TTL expires.
+type VaultProvider struct {
+	store SecretStore
+	path  string
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	creds   *Credentials
+	expires time.Time
+	closed  bool
+}
+
+var _ CredentialsProvider = (*VaultProvider)(nil)
+
+// NewVaultProvider returns a provider of the credentials stored at path in
+// store, cached for ttl. They are read on every request when ttl is zero.
+func NewVaultProvider(store SecretStore, path string, ttl time.Duration) *VaultProvider {
+	return &VaultProvider{store: store, path: path, ttl: ttl}
+}
+
+// Credentials implements CredentialsProvider interface.
+func (p *VaultProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errCredentialsClosed
+	}
+
+	if p.creds == nil || !time.Now().Before(p.expires) {
+		fields, err := p.store.ReadSecret(ctx, p.path)
+		if err != nil {
+			return nil, err
+		}
+		key, secret := string(fields["api_key"]), fields["api_secret"]
+		if key == "" || len(secret) == 0 {
+			return nil, fmt.Errorf("cryptopay: secret %s must have api_key and api_secret", p.path)
+		}
+		if p.creds != nil {
+			p.creds.Secret.Zero()
+		}
+		p.creds = &Credentials{Key: key, Secret: append(Secret(nil), secret...)}
+		p.expires = time.Now().Add(p.ttl)
+	}
+	return p.creds.copy(), nil
+}
+
+// Close zeroes the cached secret.
+func (p *VaultProvider) Close() error {
+	p.mu.Lock()
+	if p.creds != nil {
+		p.creds.Secret.Zero()
+		p.creds = nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,163 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CredentialsRotation(t *testing.T) {
+	var keys []string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(headerXMktAPIKey))
+		w.Write(getBalanceResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	p := NewStaticProvider("first-key", "first-secret")
+	c := NewClient("", "", WithHTTPClient(httpCli), WithCredentials(p))
+	if _, err := c.GetBalance(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	p.Rotate("second-key", "second-secret")
+	if _, err := c.GetBalance(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Join(keys, ",") != "first-key,second-key" {
+		t.Errorf("Expected rotated key, got %v", keys)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := c.GetBalance(); err != errCredentialsClosed {
+		t.Errorf("Expected closed credentials error, got %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected no request after close, got %d", len(keys))
+	}
+}
+
+func Test_SecretRedacted(t *testing.T) {
+	c := NewClient("some-key", "some-secret")
+	creds, err := c.PrivateService.client.creds.Credentials(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%x", "%q"} {
+		for _, v := range []interface{}{c, *c, creds, *creds, c.PrivateService.client} {
+			if out := fmt.Sprintf(format, v); strings.Contains(out, "some-secret") || strings.Contains(out, "736f6d652d736563726574") {
+				t.Errorf("Secret leaked by %s: %s", format, out)
+			}
+		}
+	}
+
+	secret := creds.Secret
+	secret.Zero()
+	if string(secret) != strings.Repeat("\x00", len("some-secret")) {
+		t.Errorf("Expected zeroed secret")
+	}
+}
+
+func Test_EnvProvider(t *testing.T) {
+	os.Setenv("TEST_CRYPTOMKT_KEY", "env-key")
+	os.Setenv("TEST_CRYPTOMKT_SECRET", "env-secret")
+	defer os.Unsetenv("TEST_CRYPTOMKT_KEY")
+	defer os.Unsetenv("TEST_CRYPTOMKT_SECRET")
+
+	creds, err := NewEnvProvider("TEST_CRYPTOMKT_KEY", "TEST_CRYPTOMKT_SECRET").Credentials(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if creds.Key != "env-key" || string(creds.Secret) != "env-secret" {
+		t.Errorf("Unexpected credentials %v", creds)
+	}
+	if _, err := NewEnvProvider("TEST_CRYPTOMKT_MISSING", "").Credentials(context.Background()); err == nil {
+		t.Errorf("Expected missing variable error")
+	}
+}
+
+func Test_FileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := ioutil.WriteFile(path, []byte(`{"api_key": "file-key", "api_secret": "file-secret"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewFileProvider(path)
+	creds, err := p.Credentials(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if creds.Key != "file-key" || string(creds.Secret) != "file-secret" {
+		t.Errorf("Unexpected credentials %v", creds)
+	}
+
+	// The file is read again once rotated.
+	if err := ioutil.WriteFile(path, []byte(`{"api_key": "rotated-key", "api_secret": "rotated-secret"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if creds, err = p.Credentials(context.Background()); err != nil || creds.Key != "rotated-key" {
+		t.Errorf("Expected rotated credentials, got %v, %v", creds, err)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Credentials(context.Background()); err == nil || !strings.Contains(err.Error(), "accessible by other users") {
+		t.Errorf("Expected permissions error, got %v", err)
+	}
+
+	p.Close()
+	if _, err := p.Credentials(context.Background()); err != errCredentialsClosed {
+		t.Errorf("Expected closed credentials error, got %v", err)
+	}
+}
+
+// mapStore is a SecretStore backed by a map.
+type mapStore struct {
+	reads   int
+	secrets map[string]map[string][]byte
+}
+
+func (ms *mapStore) ReadSecret(ctx context.Context, path string) (map[string][]byte, error) {
+	ms.reads++
+	fields, ok := ms.secrets[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return fields, nil
+}
+
+func Test_VaultProvider(t *testing.T) {
+	store := &mapStore{secrets: map[string]map[string][]byte{
+		"secret/cryptomkt": {"api_key": []byte("vault-key"), "api_secret": []byte("vault-secret")},
+	}}
+	p := NewVaultProvider(store, "secret/cryptomkt", time.Hour)
+	for i := 0; i < 2; i++ {
+		creds, err := p.Credentials(context.Background())
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		if creds.Key != "vault-key" || string(creds.Secret) != "vault-secret" {
+			t.Errorf("Unexpected credentials %v", creds)
+		}
+	}
+	if store.reads != 1 {
+		t.Errorf("Expected 1 read, got %d", store.reads)
+	}
+
+	if _, err := NewVaultProvider(store, "secret/missing", 0).Credentials(context.Background()); err == nil {
+		t.Errorf("Expected not found error")
+	}
+}
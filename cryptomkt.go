@@ -1,10 +1,13 @@
 package cryptomkt
 
 import (
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"time"
 )
 
 // Client client that hold Services.
@@ -12,6 +15,26 @@ type Client struct {
 	PaymentService
 	PublicService
 	PrivateService
+
+	// paper is attached once every option ran, so it wraps the final
+	// HTTP client whatever the order of the options.
+	paper *PaperTrader
+	// pool is set on the client AccountPool applies its options to, and err
+	// holds the first option that only applies to one account.
+	pool bool
+	err  error
+}
+
+// accountOnly reports whether c is the client of an AccountPool, recording
+// an error since option only applies to one account.
+func (c *Client) accountOnly(option string) bool {
+	if !c.pool {
+		return false
+	}
+	if c.err == nil {
+		c.err = fmt.Errorf("cryptopay: %s applies to one account, pass it to AccountPool.Add", option)
+	}
+	return true
 }
 
 // Debug method to turn on logs.
@@ -19,30 +42,160 @@ func (c *Client) Debug() {
 	log.SetOutput(os.Stdout)
 }
 
+// Option configures optional behaviour of a Client.
+type Option func(*Client)
+
+// WithTransferAllowList restricts Transfer to the given destination
+// addresses. Transfers to any other address are rejected before signing.
+func WithTransferAllowList(addresses ...string) Option {
+	return func(c *Client) {
+		if c.PrivateService.allowList == nil {
+			c.PrivateService.allowList = make(map[string]bool)
+		}
+		for _, addr := range addresses {
+			c.PrivateService.allowList[normalizeAddress(addr)] = true
+		}
+	}
+}
+
+// WithOrderJournal sets the journal used to map client order IDs to the
+// IDs assigned by CryptoMarket. NewClient uses a MemoryJournal by default.
+func WithOrderJournal(journal OrderJournal) Option {
+	return func(c *Client) {
+		if c.accountOnly("WithOrderJournal") {
+			return
+		}
+		c.PrivateService.journal = journal
+	}
+}
+
+// WithOrderValidation makes CreateOrder validate orders against market rules
+// and balances before signing them. Markets and balances are cached for ttl.
+func WithOrderValidation(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.PrivateService.validator = NewOrderValidator(&c.PrivateService, ttl)
+	}
+}
+
+// WithRiskManager makes CreateOrder check every order against the limits of
+// rm. Keep rm to use its kill switch.
+func WithRiskManager(rm *RiskManager) Option {
+	return func(c *Client) {
+		if c.accountOnly("WithRiskManager") {
+			return
+		}
+		rm.ps = &c.PrivateService
+		c.PrivateService.risk = rm
+	}
+}
+
+// WithRateLimit limits the client to n requests per period. Requests over
+// the limit wait for their turn.
+func WithRateLimit(n int, per time.Duration) Option {
+	return func(c *Client) {
+		limiter := newRateLimiter(n, per)
+		for _, hc := range c.httpClients() {
+			hc.limiter = limiter
+		}
+	}
+}
+
+// WithHTTPClient sends the requests of the client through cli, for example
+// to set timeouts or a custom transport.
+func WithHTTPClient(cli *http.Client) Option {
+	return func(c *Client) {
+		for _, hc := range c.httpClients() {
+			hc.client = cli
+		}
+	}
+}
+
+// WithPaperTrading makes PrivateService trade against pt instead of the
+// exchange.
+func WithPaperTrading(pt *PaperTrader) Option {
+	return func(c *Client) {
+		if c.accountOnly("WithPaperTrading") {
+			return
+		}
+		c.paper = pt
+	}
+}
+
+// WithDryRun makes the client write every request to w instead of sending
+// it, and return ErrDryRun. Private requests are signed with the local time
+// rather than the network time, so nothing reaches the API.
+func WithDryRun(w io.Writer) Option {
+	return func(c *Client) {
+		for _, hc := range c.httpClients() {
+			hc.dryRun = w
+		}
+	}
+}
+
+// WithCache serves public endpoints through rc. Use PublicService.Uncached
+// to skip it.
+func WithCache(rc *ResponseCache) Option {
+	return func(c *Client) {
+		for _, hc := range c.httpClients() {
+			hc.cache = rc
+		}
+	}
+}
+
+// httpClients returns the distinct http clients used by the services.
+func (c *Client) httpClients() []*httpClient {
+	clients := make([]*httpClient, 0, 3)
+	for _, hc := range []*httpClient{c.PublicService.client, c.PrivateService.client, c.PaymentService.client} {
+		if hc == nil {
+			continue
+		}
+		seen := false
+		for _, other := range clients {
+			seen = seen || other == hc
+		}
+		if !seen {
+			clients = append(clients, hc)
+		}
+	}
+	return clients
+}
+
 // NewClient instance a new cruptomkt client.
-func NewClient(APIKey, secret string) *Client {
+func NewClient(APIKey, secret string, opts ...Option) *Client {
 	priClient := &httpClient{
 		client: &http.Client{},
-		key:    APIKey,
-		secret: secret,
+		creds:  NewStaticProvider(APIKey, secret),
 	}
 
 	log.SetFlags(0)
 	log.SetOutput(ioutil.Discard)
 
-	return &Client{
-		PaymentService: PaymentService{priClient, true},
-		PrivateService: PrivateService{priClient, true},
+	c := &Client{
+		PaymentService: PaymentService{client: priClient, Private: true},
+		PrivateService: PrivateService{client: priClient, Private: true, journal: NewMemoryJournal(), cancels: &cancelLog{}},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.paper != nil {
+		c.paper.attach(c.PrivateService.client)
+	}
+
+	return c
 }
 
 // NewPublicClient expose only public endpoints.
-func NewPublicClient() *Client {
+func NewPublicClient(opts ...Option) *Client {
 	pubClient := &httpClient{
 		client: &http.Client{},
 	}
 
-	return &Client{
+	c := &Client{
 		PublicService: PublicService{pubClient, false},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
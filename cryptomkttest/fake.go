@@ -0,0 +1,227 @@
+// Package cryptomkttest provides fakes of the cryptomkt service interfaces
+// for tests.
+//
+// Every fake has one func field per method. A call runs the field and is
+// recorded; calling a method whose field is nil returns an error. The fakes
+// are safe for concurrent use.
+package cryptomkttest
+
+import (
+	"fmt"
+	"sync"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// Call represents a recorded call of a fake.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// recorder records the calls of a fake.
+type recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *recorder) record(method string, args ...interface{}) {
+	r.mu.Lock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+	r.mu.Unlock()
+}
+
+// Calls returns the calls made so far, in order.
+func (r *recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallCount returns the number of calls made so far to method.
+func (r *recorder) CallCount(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, c := range r.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset forgets the recorded calls.
+func (r *recorder) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
+
+func notImplemented(method string) error {
+	return fmt.Errorf("cryptomkttest: %s not implemented", method)
+}
+
+// PublicAPI is a fake cryptomkt.PublicAPI.
+type PublicAPI struct {
+	recorder
+
+	GetMarketsFunc    func() (*cryptomkt.MarketResponse, error)
+	GetTickerFunc     func(market string) (*cryptomkt.TickerResponse, error)
+	GetOrdersBookFunc func(opts *cryptomkt.BooksOptions) (*cryptomkt.BooksResponse, error)
+	GetTradesFunc     func(opts *cryptomkt.TradesOptions) (*cryptomkt.TradesResponse, error)
+	GetPricesFunc     func(opts *cryptomkt.PricesOptions) (*cryptomkt.PricesResponse, error)
+}
+
+var _ cryptomkt.PublicAPI = (*PublicAPI)(nil)
+
+// GetMarkets implements cryptomkt.PublicAPI interface.
+func (f *PublicAPI) GetMarkets() (*cryptomkt.MarketResponse, error) {
+	f.record("GetMarkets")
+	if f.GetMarketsFunc == nil {
+		return nil, notImplemented("GetMarkets")
+	}
+	return f.GetMarketsFunc()
+}
+
+// GetTicker implements cryptomkt.PublicAPI interface.
+func (f *PublicAPI) GetTicker(market string) (*cryptomkt.TickerResponse, error) {
+	f.record("GetTicker", market)
+	if f.GetTickerFunc == nil {
+		return nil, notImplemented("GetTicker")
+	}
+	return f.GetTickerFunc(market)
+}
+
+// GetOrdersBook implements cryptomkt.PublicAPI interface.
+func (f *PublicAPI) GetOrdersBook(opts *cryptomkt.BooksOptions) (*cryptomkt.BooksResponse, error) {
+	f.record("GetOrdersBook", opts)
+	if f.GetOrdersBookFunc == nil {
+		return nil, notImplemented("GetOrdersBook")
+	}
+	return f.GetOrdersBookFunc(opts)
+}
+
+// GetTrades implements cryptomkt.PublicAPI interface.
+func (f *PublicAPI) GetTrades(opts *cryptomkt.TradesOptions) (*cryptomkt.TradesResponse, error) {
+	f.record("GetTrades", opts)
+	if f.GetTradesFunc == nil {
+		return nil, notImplemented("GetTrades")
+	}
+	return f.GetTradesFunc(opts)
+}
+
+// GetPrices implements cryptomkt.PublicAPI interface.
+func (f *PublicAPI) GetPrices(opts *cryptomkt.PricesOptions) (*cryptomkt.PricesResponse, error) {
+	f.record("GetPrices", opts)
+	if f.GetPricesFunc == nil {
+		return nil, notImplemented("GetPrices")
+	}
+	return f.GetPricesFunc(opts)
+}
+
+// TradingAPI is a fake cryptomkt.TradingAPI.
+type TradingAPI struct {
+	recorder
+
+	CreateOrderFunc       func(mor *cryptomkt.MarketOrderRequest) (*cryptomkt.MarketOrderResponse, error)
+	CancelOrderFunc       func(cor *cryptomkt.CancelOrderRequest) (*cryptomkt.MarketOrderResponse, error)
+	GetOrderStatusFunc    func(opts *cryptomkt.OrderStatusOption) (*cryptomkt.MarketOrderResponse, error)
+	GetActiveOrdersFunc   func(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error)
+	GetExecutedOrdersFunc func(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error)
+	GetBalanceFunc        func() (*cryptomkt.BalanceResponse, error)
+}
+
+var _ cryptomkt.TradingAPI = (*TradingAPI)(nil)
+
+// CreateOrder implements cryptomkt.TradingAPI interface.
+func (f *TradingAPI) CreateOrder(mor *cryptomkt.MarketOrderRequest) (*cryptomkt.MarketOrderResponse, error) {
+	f.record("CreateOrder", mor)
+	if f.CreateOrderFunc == nil {
+		return nil, notImplemented("CreateOrder")
+	}
+	return f.CreateOrderFunc(mor)
+}
+
+// CancelOrder implements cryptomkt.TradingAPI interface.
+func (f *TradingAPI) CancelOrder(cor *cryptomkt.CancelOrderRequest) (*cryptomkt.MarketOrderResponse, error) {
+	f.record("CancelOrder", cor)
+	if f.CancelOrderFunc == nil {
+		return nil, notImplemented("CancelOrder")
+	}
+	return f.CancelOrderFunc(cor)
+}
+
+// GetOrderStatus implements cryptomkt.TradingAPI interface.
+func (f *TradingAPI) GetOrderStatus(opts *cryptomkt.OrderStatusOption) (*cryptomkt.MarketOrderResponse, error) {
+	f.record("GetOrderStatus", opts)
+	if f.GetOrderStatusFunc == nil {
+		return nil, notImplemented("GetOrderStatus")
+	}
+	return f.GetOrderStatusFunc(opts)
+}
+
+// GetActiveOrders implements cryptomkt.TradingAPI interface.
+func (f *TradingAPI) GetActiveOrders(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error) {
+	f.record("GetActiveOrders", opts)
+	if f.GetActiveOrdersFunc == nil {
+		return nil, notImplemented("GetActiveOrders")
+	}
+	return f.GetActiveOrdersFunc(opts)
+}
+
+// GetExecutedOrders implements cryptomkt.TradingAPI interface.
+func (f *TradingAPI) GetExecutedOrders(opts *cryptomkt.MarketOrderOptions) (*cryptomkt.MarketOrdersResponse, error) {
+	f.record("GetExecutedOrders", opts)
+	if f.GetExecutedOrdersFunc == nil {
+		return nil, notImplemented("GetExecutedOrders")
+	}
+	return f.GetExecutedOrdersFunc(opts)
+}
+
+// GetBalance implements cryptomkt.TradingAPI interface.
+func (f *TradingAPI) GetBalance() (*cryptomkt.BalanceResponse, error) {
+	f.record("GetBalance")
+	if f.GetBalanceFunc == nil {
+		return nil, notImplemented("GetBalance")
+	}
+	return f.GetBalanceFunc()
+}
+
+// PaymentsAPI is a fake cryptomkt.PaymentsAPI.
+type PaymentsAPI struct {
+	recorder
+
+	CreatePaymentFunc func(p *cryptomkt.PaymentRequest) (*cryptomkt.PaymentResponse, error)
+	PaymentStatusFunc func(id string) (*cryptomkt.PaymentResponse, error)
+	PaymentOrdersFunc func(opts *cryptomkt.PaymentOrdersOptions) (*cryptomkt.PaymentOrdersResponse, error)
+}
+
+var _ cryptomkt.PaymentsAPI = (*PaymentsAPI)(nil)
+
+// CreatePayment implements cryptomkt.PaymentsAPI interface.
+func (f *PaymentsAPI) CreatePayment(p *cryptomkt.PaymentRequest) (*cryptomkt.PaymentResponse, error) {
+	f.record("CreatePayment", p)
+	if f.CreatePaymentFunc == nil {
+		return nil, notImplemented("CreatePayment")
+	}
+	return f.CreatePaymentFunc(p)
+}
+
+// PaymentStatus implements cryptomkt.PaymentsAPI interface.
+func (f *PaymentsAPI) PaymentStatus(id string) (*cryptomkt.PaymentResponse, error) {
+	f.record("PaymentStatus", id)
+	if f.PaymentStatusFunc == nil {
+		return nil, notImplemented("PaymentStatus")
+	}
+	return f.PaymentStatusFunc(id)
+}
+
+// PaymentOrders implements cryptomkt.PaymentsAPI interface.
+func (f *PaymentsAPI) PaymentOrders(opts *cryptomkt.PaymentOrdersOptions) (*cryptomkt.PaymentOrdersResponse, error) {
+	f.record("PaymentOrders", opts)
+	if f.PaymentOrdersFunc == nil {
+		return nil, notImplemented("PaymentOrders")
+	}
+	return f.PaymentOrdersFunc(opts)
+}
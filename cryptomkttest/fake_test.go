@@ -0,0 +1,56 @@
+package cryptomkttest
+
+import (
+	"errors"
+	"testing"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+func Test_TradingAPI(t *testing.T) {
+	f := &TradingAPI{
+		GetBalanceFunc: func() (*cryptomkt.BalanceResponse, error) {
+			return &cryptomkt.BalanceResponse{Status: "success"}, nil
+		},
+	}
+	var api cryptomkt.TradingAPI = f
+
+	if _, err := api.GetBalance(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	mor := &cryptomkt.MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1, Price: 1000}
+	if _, err := api.CreateOrder(mor); err == nil {
+		t.Errorf("Expected not implemented error")
+	}
+
+	calls := f.Calls()
+	if len(calls) != 2 || calls[0].Method != "GetBalance" || calls[1].Method != "CreateOrder" {
+		t.Errorf("Unexpected calls %v", calls)
+		return
+	}
+	if calls[1].Args[0] != mor {
+		t.Errorf("Expected the order request as argument, got %v", calls[1].Args[0])
+	}
+	if n := f.CallCount("CreateOrder"); n != 1 {
+		t.Errorf("Expected 1 CreateOrder call, got %d", n)
+	}
+	f.Reset()
+	if len(f.Calls()) != 0 {
+		t.Errorf("Expected no calls after reset")
+	}
+}
+
+func Test_InterceptFake(t *testing.T) {
+	f := &PublicAPI{
+		GetTickerFunc: func(market string) (*cryptomkt.TickerResponse, error) {
+			return nil, &cryptomkt.TransportError{Err: errors.New("connection reset")}
+		},
+	}
+	api := cryptomkt.InterceptPublic(f, cryptomkt.RetryInterceptor(3, 0))
+	if _, err := api.GetTicker("ETHCLP"); err == nil {
+		t.Errorf("Expected error")
+	}
+	if n := f.CallCount("GetTicker"); n != 3 {
+		t.Errorf("Expected 3 GetTicker calls, got %d", n)
+	}
+}
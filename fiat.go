@@ -0,0 +1,115 @@
+package cryptomkt
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+// FiatDepositRequest represents the notification of a bank deposit.
+type FiatDepositRequest struct {
+	// Monto depositado
+	Amount float64
+	// ID de la cuenta bancaria desde la cual se realizó el depósito
+	BankAccount string
+	// Comprobante del depósito
+	Voucher io.Reader
+	// Nombre del archivo del comprobante, por ejemplo voucher.pdf
+	VoucherFilename string
+}
+
+// Params returns a map used to sign the requests.
+func (fdr *FiatDepositRequest) Params() url.Values {
+	form := url.Values{}
+
+	form.Add("amount", strconv.FormatFloat(fdr.Amount, 'f', -1, 64))
+	form.Add("bank_account", fdr.BankAccount)
+
+	return form
+}
+
+// FiatWithdrawalRequest represents the request of a bank withdrawal.
+type FiatWithdrawalRequest struct {
+	// Monto a retirar
+	Amount float64
+	// ID de la cuenta bancaria de destino
+	BankAccount string
+}
+
+// Params returns a map used to sign the requests.
+func (fwr *FiatWithdrawalRequest) Params() url.Values {
+	form := url.Values{}
+
+	form.Add("amount", strconv.FormatFloat(fwr.Amount, 'f', -1, 64))
+	form.Add("bank_account", fwr.BankAccount)
+
+	return form
+}
+
+// NotificationResponse represents the response of a fiat deposit or
+// withdrawal notification.
+type NotificationResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   string `json:"data,omitempty"`
+}
+
+// NotifyDeposit notifies CryptoMarket of a bank deposit, uploading its
+// voucher.
+func (ps *PrivateService) NotifyDeposit(fdr *FiatDepositRequest) (*NotificationResponse, error) {
+	if fdr.Amount <= 0 {
+		return nil, errors.New("cryptopay: deposit amount must be positive")
+	}
+	if fdr.BankAccount == "" {
+		return nil, errors.New("cryptopay: deposit bank account is required")
+	}
+	if fdr.Voucher == nil {
+		return nil, errors.New("cryptopay: deposit voucher is required")
+	}
+
+	filename := fdr.VoucherFilename
+	if filename == "" {
+		filename = "voucher"
+	}
+
+	ps.client.SetPrivate(ps.Private)
+	resp, err := ps.client.postMultipart("/request/deposit", fdr.Params(), formFile{
+		field:    "voucher",
+		filename: filename,
+		content:  fdr.Voucher,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nr NotificationResponse
+	if err := unmarshalJSON(resp.Body, &nr); err != nil {
+		return nil, err
+	}
+
+	return &nr, nil
+}
+
+// NotifyWithdrawal requests a withdrawal from the fiat wallet to a bank
+// account.
+func (ps *PrivateService) NotifyWithdrawal(fwr *FiatWithdrawalRequest) (*NotificationResponse, error) {
+	if fwr.Amount <= 0 {
+		return nil, errors.New("cryptopay: withdrawal amount must be positive")
+	}
+	if fwr.BankAccount == "" {
+		return nil, errors.New("cryptopay: withdrawal bank account is required")
+	}
+
+	ps.client.SetPrivate(ps.Private)
+	resp, err := ps.client.postForm("/request/withdrawal", fwr.Params())
+	if err != nil {
+		return nil, err
+	}
+
+	var nr NotificationResponse
+	if err := unmarshalJSON(resp.Body, &nr); err != nil {
+		return nil, err
+	}
+
+	return &nr, nil
+}
@@ -0,0 +1,108 @@
+package cryptomkt
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_NotifyDeposit(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+
+		f, fh, err := r.FormFile("voucher")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		defer f.Close()
+		content, _ := ioutil.ReadAll(f)
+		if string(content) != "%PDF-1.4" || fh.Filename != "voucher.pdf" {
+			t.Errorf("Unexpected voucher %s with content %q", fh.Filename, content)
+		}
+
+		sig := hmac.New(sha512.New384, []byte("some-secret"))
+		sig.Write([]byte(r.Header.Get(headerXMktTimestamp) + r.URL.Path + "150000" + "12345"))
+		expectedSign := hex.EncodeToString(sig.Sum(nil))
+		if r.Header.Get(headerXMktSignature) != expectedSign {
+			t.Errorf("Expected signature %s, got %s", expectedSign, r.Header.Get(headerXMktSignature))
+		}
+
+		w.Write(getNotificationResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	fdr := &FiatDepositRequest{
+		Amount:          150000,
+		BankAccount:     "12345",
+		Voucher:         strings.NewReader("%PDF-1.4"),
+		VoucherFilename: "voucher.pdf",
+	}
+	nr, err := ps.NotifyDeposit(fdr)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expected := "success"
+	if nr.Status != expected {
+		t.Errorf("Expected status %s, got %s", expected, nr.Status)
+	}
+}
+
+func Test_NotifyWithdrawal(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("bank_account") != "12345" {
+			t.Errorf("Expected bank account 12345, got %s", r.FormValue("bank_account"))
+		}
+		w.Write(getNotificationResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	if _, err := ps.NotifyWithdrawal(&FiatWithdrawalRequest{Amount: 5000}); err == nil {
+		t.Errorf("Expected error for missing bank account")
+	}
+
+	nr, err := ps.NotifyWithdrawal(&FiatWithdrawalRequest{Amount: 5000, BankAccount: "12345"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expected := "success"
+	if nr.Status != expected {
+		t.Errorf("Expected status %s, got %s", expected, nr.Status)
+	}
+}
+
+var getNotificationResponse = []byte(`
+	{
+		"status": "success",
+		"data": ""
+	}
+`)
@@ -2,19 +2,26 @@ package cryptomkt
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bt51/ntpclient"
 )
@@ -39,6 +46,9 @@ const (
 	statusSuccessfulPayment = 3
 
 	ntpServer = "2.cl.pool.ntp.org"
+
+	// dateLayout is the layout of the dates returned by the API.
+	dateLayout = "2006-01-02T15:04:05.999999"
 )
 
 // StatusCodeToText ...
@@ -68,8 +78,16 @@ func StatusCodeToText(status int) string {
 // httpClient represent a base struct to store Http client configuration
 type httpClient struct {
 	client  *http.Client
-	key     string
-	secret  string
+	creds   CredentialsProvider
+	limiter *rateLimiter
+	cache   *ResponseCache
+	// noCache makes every request skip the cache.
+	noCache     bool
+	middlewares []Middleware
+	// dryRun receives the requests instead of the API when set.
+	dryRun io.Writer
+
+	mu      sync.RWMutex
 	private bool
 }
 
@@ -85,89 +103,174 @@ func (err *APIError) Error() string {
 	return fmt.Sprintf("cryptopay: %v", err.Message)
 }
 
+// ErrDryRun is returned by every request of a client created with
+// WithDryRun.
+var ErrDryRun = errors.New("cryptopay: dry run")
+
+// TransportError is returned when a request failed before the whole answer
+// of the API was read, so whether the API acted on it is unknown.
+type TransportError struct {
+	Err error
+}
+
+// Error implements error interface.
+func (err *TransportError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *TransportError) Unwrap() error {
+	return err.Err
+}
+
 func (hc *httpClient) SetPrivate(private bool) {
+	hc.mu.Lock()
 	hc.private = private
+	hc.mu.Unlock()
 }
 
-func (hc *httpClient) do(req *http.Request, values url.Values) (*http.Response, error) {
-	t, err := ntpclient.GetNetworkTime(ntpServer, 123)
-	if err != nil {
-		return nil, err
-	}
-	now := t.Unix()
+func (hc *httpClient) isPrivate() bool {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+	return hc.private
+}
 
+func (hc *httpClient) do(req *http.Request, values url.Values) (*http.Response, error) {
 	req.Header.Set("Accept", "application/json")
-	if req.Method == http.MethodPost {
+	if req.Method == http.MethodPost && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	if hc.private {
-		req.Header.Set(headerXMktAPIKey, hc.key)
-		hc.signRequest(req, values, now)
+	// Dry runs sign with the local time: they neither wait for the rate
+	// limiter nor reach the network.
+	now := time.Now().Unix()
+	if hc.dryRun == nil {
+		if hc.limiter != nil {
+			if err := hc.limiter.wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		t, err := ntpclient.GetNetworkTime(ntpServer, 123)
+		if err != nil {
+			return nil, err
+		}
+		now = t.Unix()
+	}
+
+	if hc.isPrivate() {
+		if hc.creds == nil {
+			return nil, errors.New("cryptopay: no credentials")
+		}
+		creds, err := hc.creds.Credentials(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(headerXMktAPIKey, creds.Key)
+		hc.signRequest(req, values, now, creds.Secret)
+		creds.Secret.Zero()
 		req.Header.Set(headerXMktTimestamp, fmt.Sprintf("%d", now))
 	}
 
-	resp, err := hc.client.Do(req)
+	if hc.dryRun != nil {
+		dump, err := httputil.DumpRequestOut(req, true)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(hc.dryRun, "%s\n", dump)
+		return nil, ErrDryRun
+	}
+
+	r := &Request{
+		Operation: operationOf(req.Method, endpoint(req.URL.Path)),
+		HTTP:      req,
+		Values:    values,
+	}
+	res, err := hc.handler()(r)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       &resultBody{Reader: bytes.NewReader(res.Body), value: res.Value},
+		Request:    r.HTTP,
+	}, nil
+}
+
+// resultBody is the body of a response returned by the middlewares. It
+// carries the decoded value of the result so unmarshalJSON does not decode
+// the body twice.
+type resultBody struct {
+	*bytes.Reader
+	value interface{}
+}
+
+// Close implements io.Closer interface.
+func (rb *resultBody) Close() error {
+	return nil
+}
+
+// send sends req and reads the response. Successful responses are decoded
+// into the Value of the result, error statuses into an *APIError and
+// failures to send or read into a *TransportError.
+func (hc *httpClient) send(req *Request) (*Result, error) {
+	resp, err := hc.client.Do(req.HTTP)
 	if err != nil {
-		return nil, fmt.Errorf("client: %s resquest failed, %v", req.URL, err)
+		return nil, &TransportError{Err: fmt.Errorf("client: %s resquest failed, %w", req.HTTP.URL, err)}
 	}
+	defer resp.Body.Close()
 
 	log.Println(resp.StatusCode)
-	// TODO check error response
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Err: fmt.Errorf("client: %s resquest failed, %w", req.HTTP.URL, err)}
+	}
+	res := &Result{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+
 	switch resp.StatusCode {
-	case http.StatusBadRequest:
-		var valErr APIError
-		if err = unmarshalJSON(resp.Body, &valErr); err != nil {
-			return nil, fmt.Errorf("cryptopay: error parsing response, %v", err)
-		}
-		return nil, &valErr
-	case http.StatusUnauthorized:
-		var authErr APIError
-		if err = unmarshalJSON(resp.Body, &authErr); err != nil {
-			return nil, fmt.Errorf("cryptopay: error parsing response, %v", err)
-		}
-		return nil, &authErr
-	case http.StatusServiceUnavailable:
-		var svcErr APIError
-		if err = unmarshalJSON(resp.Body, &svcErr); err != nil {
-			return nil, fmt.Errorf("cryptopay: error parsing response, %v", err)
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusNotFound, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		var apiErr APIError
+		if err = res.Decode(&apiErr); err != nil {
+			return res, fmt.Errorf("cryptopay: error parsing response, %v", err)
 		}
-		return nil, &svcErr
-	case http.StatusTooManyRequests:
-		var svcErr APIError
-		if err = unmarshalJSON(resp.Body, &svcErr); err != nil {
-			return nil, fmt.Errorf("cryptopay: error parsing response, %v", err)
-		}
-		return nil, &svcErr
-	case http.StatusForbidden:
-		var svcErr APIError
-		if err = unmarshalJSON(resp.Body, &svcErr); err != nil {
-			return nil, fmt.Errorf("cryptopay: error parsing response, %v", err)
-		}
-		return nil, &svcErr
-	case http.StatusNotFound:
-		var svcErr APIError
-		if err = unmarshalJSON(resp.Body, &svcErr); err != nil {
-			return nil, fmt.Errorf("cryptopay: error parsing response, %v", err)
-		}
-		return nil, &svcErr
+		return res, &apiErr
 	default:
-		return resp, nil
+		if newValue, ok := responseTypes[req.Operation]; ok {
+			if v := newValue(); res.Decode(v) == nil {
+				res.Value = v
+			}
+		}
+		return res, nil
 	}
 }
 
 func (hc *httpClient) get(path string, values url.Values) (*http.Response, error) {
+	return hc.getContext(context.Background(), path, values)
+}
+
+// getContext is like get but the request, including the wait on the rate
+// limiter, is bound to ctx.
+func (hc *httpClient) getContext(ctx context.Context, path string, values url.Values) (*http.Response, error) {
+	if resp, ok, err := hc.getCached(ctx, path, values); ok {
+		return resp, err
+	}
+	return hc.getUncached(ctx, path, values)
+}
+
+func (hc *httpClient) getUncached(ctx context.Context, path string, values url.Values) (*http.Response, error) {
 	uri := baseURL.String() + path
 
 	if values == nil {
-		req, err := http.NewRequest(http.MethodGet, uri, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
 		if err != nil {
 			return nil, err
 		}
 		return hc.do(req, values)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, uri, strings.NewReader(values.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -175,16 +278,62 @@ func (hc *httpClient) get(path string, values url.Values) (*http.Response, error
 }
 
 func (hc *httpClient) postForm(path string, values url.Values) (*http.Response, error) {
+	return hc.postFormContext(context.Background(), path, values)
+}
+
+// postFormContext is like postForm but the request, including the wait on
+// the rate limiter, is bound to ctx.
+func (hc *httpClient) postFormContext(ctx context.Context, path string, values url.Values) (*http.Response, error) {
 	url := baseURL.String() + path
 
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(values.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
 	return hc.do(req, values)
 }
 
-func (hc *httpClient) signRequest(req *http.Request, values url.Values, timestamp int64) {
+// formFile represents a file attached to a multipart request.
+type formFile struct {
+	field    string
+	filename string
+	content  io.Reader
+}
+
+// postMultipart sends values and files as a multipart/form-data body. Only
+// values take part in the signature, files are sent as they are.
+func (hc *httpClient) postMultipart(path string, values url.Values, files ...formFile) (*http.Response, error) {
+	url := baseURL.String() + path
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k := range values {
+		if err := mw.WriteField(k, values.Get(k)); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		fw, err := mw.CreateFormFile(f.field, f.filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(fw, f.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return hc.do(req, values)
+}
+
+func (hc *httpClient) signRequest(req *http.Request, values url.Values, timestamp int64, secret []byte) {
 	var buff bytes.Buffer
 	buff.WriteString(fmt.Sprintf("%d", timestamp))
 	buff.WriteString(req.URL.Path)
@@ -200,7 +349,7 @@ func (hc *httpClient) signRequest(req *http.Request, values url.Values, timestam
 			buff.WriteString(values[k][0])
 		}
 		break
-	case "/v1/payment/new_order":
+	case "/v1/payment/new_order", "/v1/transfer", "/v1/request/deposit", "/v1/request/withdrawal":
 		if values != nil {
 			keys := make([]string, 0)
 			for k := range values {
@@ -214,7 +363,7 @@ func (hc *httpClient) signRequest(req *http.Request, values url.Values, timestam
 		break
 	}
 
-	sig := hmac.New(sha512.New384, []byte(hc.secret))
+	sig := hmac.New(sha512.New384, secret)
 	sig.Write(buff.Bytes())
 	sign := hex.EncodeToString(sig.Sum(nil))
 	buff.Reset()
@@ -225,6 +374,15 @@ func (hc *httpClient) signRequest(req *http.Request, values url.Values, timestam
 func unmarshalJSON(r io.ReadCloser, v interface{}) error {
 	defer r.Close()
 
+	// Use the value decoded by send when it has the type of v.
+	if rb, ok := r.(*resultBody); ok && rb.value != nil {
+		dst, src := reflect.ValueOf(v), reflect.ValueOf(rb.value)
+		if dst.Type() == src.Type() {
+			dst.Elem().Set(src.Elem())
+			return nil
+		}
+	}
+
 	body, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
@@ -0,0 +1,197 @@
+package cryptomkt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotentAttempts is the number of times CreateOrderIdempotent sends
+	// an order before giving up.
+	idempotentAttempts = 3
+	// clockSkew is the tolerance used when comparing local and server dates.
+	clockSkew = time.Minute
+)
+
+// isAmbiguous reports whether err leaves unknown if the request reached the
+// API. Only failures to send the request or to read the answer are; errors
+// raised locally before sending, such as risk, validation or rate limiter
+// errors, and errors returned by the API mean the order was not created.
+func isAmbiguous(err error) bool {
+	var nsErr *notSentError
+	if errors.As(err, &nsErr) {
+		return false
+	}
+	var tErr *TransportError
+	return errors.As(err, &tErr)
+}
+
+// notSentError wraps a *TransportError of a request made before sending an
+// order, such as the balance fetched to validate it. The order itself was
+// not sent, so the error is not ambiguous.
+type notSentError struct {
+	err error
+}
+
+func (err *notSentError) Error() string {
+	return err.err.Error()
+}
+
+func (err *notSentError) Unwrap() error {
+	return err.err
+}
+
+// notSent marks err as raised before the order was sent. Other errors are
+// returned unchanged, so their type can still be asserted.
+func notSent(err error) error {
+	if isAmbiguous(err) {
+		return &notSentError{err: err}
+	}
+	return err
+}
+
+// CreateOrderIdempotent creates an order that can be safely retried. The
+// request must carry a ClientOrderID: if the journal already knows it, the
+// existing order is returned. When CreateOrder fails without an answer from
+// the API, active, executed and cancelled orders are searched for a
+// matching order created since the attempt; it is only sent again once that search proved
+// the order does not exist.
+func (ps *PrivateService) CreateOrderIdempotent(mor *MarketOrderRequest) (*MarketOrderResponse, error) {
+	if mor.ClientOrderID == "" {
+		return nil, errors.New("cryptopay: client order ID is required")
+	}
+	if ps.journal == nil {
+		return nil, errors.New("cryptopay: no order journal configured")
+	}
+
+	if id, ok := ps.journal.OrderID(mor.ClientOrderID); ok {
+		return ps.GetOrderStatus(&OrderStatusOption{ID: id})
+	}
+
+	var err error
+	for attempt := 0; attempt < idempotentAttempts; attempt++ {
+		start := time.Now()
+
+		var morr *MarketOrderResponse
+		morr, err = ps.CreateOrder(mor)
+		if err == nil || !isAmbiguous(err) {
+			return morr, err
+		}
+
+		o, rerr := ps.reconcileOrder(mor, start)
+		if rerr != nil {
+			return nil, fmt.Errorf("cryptopay: order %s state unknown: %w (reconcile: %v)", mor.ClientOrderID, err, rerr)
+		}
+		if o != nil {
+			if err := ps.journal.Record(mor.ClientOrderID, o.ID); err != nil {
+				return nil, err
+			}
+			return &MarketOrderResponse{Status: "success", Data: o}, nil
+		}
+	}
+
+	return nil, err
+}
+
+// reconcileOrder looks for an order matching mor created after since that is
+// not already known by the journal. Active and executed orders are walked
+// page by page until a page only holds orders last updated before since.
+// The API does not list orders cancelled before any fill, so the orders
+// cancelled through the client are searched too.
+func (ps *PrivateService) reconcileOrder(mor *MarketOrderRequest, since time.Time) (*MarketOrder, error) {
+	if ps.cancels != nil {
+		for _, o := range ps.cancels.list() {
+			if matchesOrder(o, mor, since) {
+				if _, known := ps.journal.ClientOrderID(o.ID); !known {
+					return o, nil
+				}
+			}
+		}
+	}
+
+	for _, list := range []func(*MarketOrderOptions) (*MarketOrdersResponse, error){ps.GetActiveOrders, ps.GetExecutedOrders} {
+		opts := &MarketOrderOptions{Market: mor.Market, Limit: 100}
+		for {
+			resp, err := list(opts)
+			if err != nil {
+				return nil, err
+			}
+			recent := false
+			for _, o := range resp.Data {
+				if matchesOrder(o, mor, since) {
+					if _, known := ps.journal.ClientOrderID(o.ID); !known {
+						return o, nil
+					}
+				}
+				recent = recent || !updatedBefore(o, since)
+			}
+			if !recent || resp.Pagination == nil || int(resp.Pagination.Next) <= opts.Page {
+				break
+			}
+			opts.Page = int(resp.Pagination.Next)
+		}
+	}
+
+	return nil, nil
+}
+
+// updatedBefore reports whether the last date of o, its execution, update or
+// creation date, is before since, allowing for clock skew. Orders without a
+// valid date are not.
+func updatedBefore(o *MarketOrder, since time.Time) bool {
+	last := time.Time{}
+	for _, date := range []string{o.CreatedAt, o.UpdatedAt, o.ExecutedAt} {
+		t, err := time.Parse(dateLayout, date)
+		if err != nil {
+			continue
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return !last.IsZero() && last.Before(since.Add(-clockSkew))
+}
+
+// maxCancelLog is the number of cancelled orders kept by cancelLog.
+const maxCancelLog = 1000
+
+// cancelLog keeps the last orders cancelled through a client.
+type cancelLog struct {
+	mu     sync.Mutex
+	orders []*MarketOrder
+}
+
+func (cl *cancelLog) add(o *MarketOrder) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.orders = append(cl.orders, o)
+	if len(cl.orders) > maxCancelLog {
+		cl.orders = cl.orders[len(cl.orders)-maxCancelLog:]
+	}
+}
+
+func (cl *cancelLog) list() []*MarketOrder {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return append([]*MarketOrder(nil), cl.orders...)
+}
+
+// matchesOrder reports whether o could have been created by mor after since.
+func matchesOrder(o *MarketOrder, mor *MarketOrderRequest, since time.Time) bool {
+	if !strings.EqualFold(o.Market, mor.Market) || o.Type != mor.Type || o.Price != int64(mor.Price) {
+		return false
+	}
+	if o.Amount == nil || math.Abs(o.Amount.Original-mor.Amount) > 1e-9 {
+		return false
+	}
+
+	createdAt, err := time.Parse(dateLayout, o.CreatedAt)
+	if err != nil {
+		return false
+	}
+	return !createdAt.Before(since.Add(-clockSkew))
+}
@@ -0,0 +1,254 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_CreateOrderIdempotent(t *testing.T) {
+	creates := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/orders":
+			creates++
+			// The order is created but the connection drops before answering.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case "/v1/orders/active":
+			fmt.Fprintf(w, getReconcileActiveOrdersResponse, time.Now().UTC().Format(dateLayout))
+		case "/v1/orders/status":
+			if r.URL.Query().Get("id") != "M104000" {
+				t.Errorf("Expected status of M104000, got %s", r.URL.Query().Get("id"))
+			}
+			w.Write(getStatusOrderResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+		journal: NewMemoryJournal(),
+	}
+
+	mor := &MarketOrderRequest{
+		Market:        "ETHCLP",
+		Amount:        0.3,
+		Price:         10000,
+		Type:          "buy",
+		ClientOrderID: "my-order-1",
+	}
+	morr, err := ps.CreateOrderIdempotent(mor)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expectedID := "M104000"
+	if morr.Data.ID != expectedID {
+		t.Errorf("Expected order %s, got %s", expectedID, morr.Data.ID)
+		return
+	}
+	if creates != 1 {
+		t.Errorf("Expected 1 create request, got %d", creates)
+	}
+
+	if _, err := ps.CreateOrderIdempotent(mor); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if creates != 1 {
+		t.Errorf("Expected order not to be created again, got %d create requests", creates)
+	}
+}
+
+func Test_CreateOrderIdempotent_ReconcilePages(t *testing.T) {
+	creates := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().UTC().Format(dateLayout)
+		switch r.URL.Path {
+		case "/v1/orders":
+			creates++
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case "/v1/orders/active":
+			if r.URL.Query().Get("page") == "" {
+				fmt.Fprintf(w, `{"status": "success", "pagination": {"page": 0, "next": 1}, "data": [
+					{"status": "active", "created_at": "%s", "amount": {"original": "1", "remaining": "1"}, "price": "9000", "type": "buy", "id": "M104001", "market": "ETHCLP"}
+				]}`, now)
+				return
+			}
+			fmt.Fprintf(w, `{"status": "success", "pagination": {"page": 1, "next": "null"}, "data": [
+				{"status": "active", "created_at": "%s", "amount": {"original": "0.3", "remaining": "0.3"}, "price": "10000", "type": "buy", "id": "M104000", "market": "ETHCLP"}
+			]}`, now)
+		case "/v1/orders/cancel":
+			fmt.Fprintf(w, `{"status": "success", "data": {"status": "cancelled", "created_at": "%s", "updated_at": "%s", "amount": {"executed": "0", "original": "0.2"}, "price": "10000", "type": "sell", "id": "M104002", "market": "ETHCLP"}}`, now, now)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	c := NewClient("some-key", "some-secret", WithHTTPClient(httpCli))
+
+	// The matching active order is on the second page.
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy", ClientOrderID: "my-order-1"}
+	if morr, err := c.CreateOrderIdempotent(mor); err != nil || morr.Data.ID != "M104000" {
+		t.Errorf("Expected order M104000, got %+v and %v", morr, err)
+	}
+
+	// Orders cancelled before any fill are not listed by the API.
+	if _, err := c.CancelOrder(&CancelOrderRequest{ID: "M104002"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	mor = &MarketOrderRequest{Market: "ETHCLP", Amount: 0.2, Price: 10000, Type: "sell", ClientOrderID: "my-order-2"}
+	if morr, err := c.CreateOrderIdempotent(mor); err != nil || morr.Data.ID != "M104002" {
+		t.Errorf("Expected cancelled order M104002, got %+v and %v", morr, err)
+	}
+	if creates != 2 {
+		t.Errorf("Expected every order to be sent once, got %d create requests", creates)
+	}
+}
+
+func Test_CreateOrderIdempotent_ReconcileFails(t *testing.T) {
+	creates := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/orders":
+			creates++
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case "/v1/orders/active":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write(getInvalidOrderResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+		journal: NewMemoryJournal(),
+	}
+
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy", ClientOrderID: "my-order-1"}
+	_, err := ps.CreateOrderIdempotent(mor)
+	var tErr *TransportError
+	if !errors.As(err, &tErr) {
+		t.Errorf("Expected ambiguous error, got %v", err)
+	}
+	if creates != 1 {
+		t.Errorf("Expected 1 create request, got %d", creates)
+	}
+}
+
+func Test_CreateOrderIdempotent_LocalError(t *testing.T) {
+	requests := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rm := NewRiskManager(RiskLimits{})
+	c := NewClient("some-key", "some-secret", WithRiskManager(rm))
+	c.PrivateService.client.client = httpCli
+	if _, err := rm.Kill(context.Background(), false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy", ClientOrderID: "my-order-1"}
+	var riskErr *RiskError
+	if _, err := c.CreateOrderIdempotent(mor); !errors.As(err, &riskErr) {
+		t.Errorf("Expected kill switch error, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests, got %d", requests)
+	}
+	if v := rm.dailyVolume("ETHCLP"); v != 0 {
+		t.Errorf("Expected no volume recorded, got %v", v)
+	}
+}
+
+func Test_FileJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+
+	fj, err := NewFileJournal(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if err := fj.Record("my-order-1", "M103975"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	fj.Close()
+
+	fj, err = NewFileJournal(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	defer fj.Close()
+
+	id, ok := fj.OrderID("my-order-1")
+	if !ok || id != "M103975" {
+		t.Errorf("Expected order M103975, got %s", id)
+	}
+	if cid, ok := fj.ClientOrderID("M103975"); !ok || cid != "my-order-1" {
+		t.Errorf("Expected client order my-order-1, got %s", cid)
+	}
+}
+
+const getReconcileActiveOrdersResponse = `
+	{
+		"status": "success",
+		"pagination": {
+		   "previous": "null",
+		   "limit": 100,
+		   "page": 0,
+		   "next": "null"
+		},
+		"data": [
+		   {
+			  "status": "active",
+			  "created_at": "2017-09-01T14:01:56.887272",
+			  "amount": {
+				 "original": "0.3",
+				 "remaining": "0.3"
+			  },
+			  "price": "10000",
+			  "type": "buy",
+			  "id": "M103966",
+			  "market": "ETHCLP"
+		   },
+		   {
+			  "status": "active",
+			  "created_at": "%s",
+			  "amount": {
+				 "original": "0.3",
+				 "remaining": "0.3"
+			  },
+			  "price": "10000",
+			  "type": "buy",
+			  "id": "M104000",
+			  "market": "ETHCLP"
+		   }
+		]
+	 }
+`
@@ -0,0 +1,47 @@
+package cryptomkt
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes a request sent to the API.
+type RequestInfo struct {
+	Operation Operation
+	// Endpoint is the path of the request without the version, for example
+	// /orders/active.
+	Endpoint string
+	// Method is the HTTP method.
+	Method string
+	// StatusCode is the HTTP status of the response, zero when none was
+	// received.
+	StatusCode int
+	// Duration is the time spent waiting for the response, without the wait
+	// on the rate limiter.
+	Duration time.Duration
+	Err      error
+}
+
+// Instrumentation observes the requests sent to the API. Responses served
+// from the cache are not requests. Methods are called concurrently.
+type Instrumentation interface {
+	// StartRequest is called before a request is sent, with Endpoint and
+	// Method set. The returned context is used for the request and passed
+	// to EndRequest.
+	StartRequest(ctx context.Context, info *RequestInfo) context.Context
+	// EndRequest is called once the response is read or the request failed.
+	EndRequest(ctx context.Context, info *RequestInfo)
+}
+
+// WithInstrumentation reports every request of the client to inst. When
+// given several times, every instrumentation observes the requests in the
+// order they were given.
+func WithInstrumentation(inst Instrumentation) Option {
+	return WithMiddleware(InstrumentationMiddleware(inst))
+}
+
+// endpoint returns the path of a request URL without the API version.
+func endpoint(path string) string {
+	return strings.TrimPrefix(path, "/"+baseURL.Path)
+}
@@ -0,0 +1,78 @@
+package cryptomkt
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// recordingInstrumentation records the requests it observes.
+type recordingInstrumentation struct {
+	mu       sync.Mutex
+	started  int
+	requests []RequestInfo
+}
+
+func (ri *recordingInstrumentation) StartRequest(ctx context.Context, info *RequestInfo) context.Context {
+	ri.mu.Lock()
+	ri.started++
+	ri.mu.Unlock()
+	return ctx
+}
+
+func (ri *recordingInstrumentation) EndRequest(ctx context.Context, info *RequestInfo) {
+	ri.mu.Lock()
+	ri.requests = append(ri.requests, *info)
+	ri.mu.Unlock()
+}
+
+func Test_WithInstrumentation(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/balance":
+			w.Write(getBalanceResponse)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status": "error", "message": "invalid_key"}`))
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	inst, other := &recordingInstrumentation{}, &recordingInstrumentation{}
+	c := NewClient("some-key", "some-secret", WithHTTPClient(httpCli), WithInstrumentation(inst), WithInstrumentation(other))
+
+	if _, err := c.GetBalance(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := c.GetActiveOrders(&MarketOrderOptions{Market: "ETHCLP"}); err == nil {
+		t.Errorf("Expected unauthorized error")
+	}
+
+	if other.started != 2 || len(other.requests) != 2 {
+		t.Errorf("Expected 2 requests on every instrumentation, got %d", len(other.requests))
+	}
+	if inst.started != 2 || len(inst.requests) != 2 {
+		t.Errorf("Expected 2 requests, got %d started and %d ended", inst.started, len(inst.requests))
+		return
+	}
+	cases := []struct {
+		op       Operation
+		endpoint string
+		status   int
+		err      bool
+	}{
+		{OperationGetBalance, "/balance", http.StatusOK, false},
+		{OperationGetActiveOrders, "/orders/active", http.StatusUnauthorized, true},
+	}
+	for i, c := range cases {
+		r := inst.requests[i]
+		if r.Operation != c.op || r.Endpoint != c.endpoint || r.Method != http.MethodGet || r.StatusCode != c.status || (r.Err != nil) != c.err {
+			t.Errorf("Expected %s %d (error %v), got %+v", c.endpoint, c.status, c.err, r)
+		}
+		if r.Duration <= 0 {
+			t.Errorf("Expected duration of %s", c.endpoint)
+		}
+	}
+}
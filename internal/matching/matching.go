@@ -0,0 +1,156 @@
+// Package matching fills simulated orders and keeps the funds they lock. It
+// is shared by the paper trader and the backtester so both simulate the
+// exchange the same way.
+package matching
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Order is a simulated order.
+type Order struct {
+	ID     string
+	Market string
+	// Base and Quote are the currencies of Market.
+	Base  string
+	Quote string
+	// Type is buy or sell.
+	Type string
+	// Status is active, executed or cancelled.
+	Status string
+	Price  int64
+
+	Original  float64
+	Remaining float64
+	Executed  float64
+
+	ExecutionPrice    float64
+	AvgExecutionPrice int64
+
+	CreatedAt  string
+	UpdatedAt  string
+	ExecutedAt string
+
+	// Locked is the amount still locked by the order, in quote currency for
+	// buy orders and in base currency for sell orders.
+	Locked float64
+	// Notional is the executed amount times the price of each fill.
+	Notional float64
+}
+
+// Crosses reports whether o can be filled at price.
+func (o *Order) Crosses(price float64) bool {
+	if o.Type == "buy" {
+		return price <= float64(o.Price)
+	}
+	return price >= float64(o.Price)
+}
+
+// Account holds the simulated balances. It is not safe for concurrent use.
+type Account struct {
+	Available map[string]float64
+	Locked    map[string]float64
+	// Fees and Volume sum the fees charged and the notional of every fill.
+	Fees   float64
+	Volume float64
+}
+
+// NewAccount returns an account with the given available balances.
+func NewAccount(balances map[string]float64) *Account {
+	a := &Account{
+		Available: make(map[string]float64),
+		Locked:    make(map[string]float64),
+	}
+	for currency, amount := range balances {
+		a.Available[strings.ToUpper(currency)] = amount
+	}
+	return a
+}
+
+// Balance returns the available plus the locked funds of currency.
+func (a *Account) Balance(currency string) float64 {
+	return a.Available[currency] + a.Locked[currency]
+}
+
+// Wallets returns the currencies of the account, sorted.
+func (a *Account) Wallets() []string {
+	wallets := make([]string, 0, len(a.Available))
+	for c := range a.Available {
+		wallets = append(wallets, c)
+	}
+	for c := range a.Locked {
+		if _, ok := a.Available[c]; !ok {
+			wallets = append(wallets, c)
+		}
+	}
+	sort.Strings(wallets)
+	return wallets
+}
+
+// Lock locks the funds a new order needs, buy orders being priced with fee,
+// and reports false when they are not available.
+func (a *Account) Lock(o *Order, fee float64) bool {
+	currency, need := o.Base, o.Original
+	if o.Type == "buy" {
+		currency, need = o.Quote, o.Original*float64(o.Price)*(1+fee)
+	}
+	if a.Available[currency] < need {
+		return false
+	}
+	a.Available[currency] -= need
+	a.Locked[currency] += need
+	o.Locked = need
+	return true
+}
+
+// Fill executes qty of o at price, charging fee in the quote currency. now
+// is the date of the fill.
+func (a *Account) Fill(o *Order, qty, price, fee float64, now string) {
+	notional := qty * price
+	if o.Type == "buy" {
+		cost := notional * (1 + fee)
+		o.Locked -= cost
+		a.Locked[o.Quote] -= cost
+		a.Available[o.Base] += qty
+	} else {
+		o.Locked -= qty
+		a.Locked[o.Base] -= qty
+		a.Available[o.Quote] += notional * (1 - fee)
+	}
+	a.Fees += notional * fee
+	a.Volume += notional
+
+	o.Notional += notional
+	o.Remaining -= qty
+	o.Executed += qty
+	o.ExecutionPrice = price
+	o.AvgExecutionPrice = int64(math.Round(o.Notional / o.Executed))
+	o.UpdatedAt = now
+
+	if o.Remaining <= 1e-12 {
+		o.Remaining = 0
+		o.Status = "executed"
+		o.ExecutedAt = now
+		a.Release(o)
+	}
+}
+
+// Cancel cancels o and unlocks its funds.
+func (a *Account) Cancel(o *Order, now string) {
+	o.Status = "cancelled"
+	o.UpdatedAt = now
+	a.Release(o)
+}
+
+// Release unlocks the funds still locked by o.
+func (a *Account) Release(o *Order) {
+	currency := o.Base
+	if o.Type == "buy" {
+		currency = o.Quote
+	}
+	a.Locked[currency] -= o.Locked
+	a.Available[currency] += o.Locked
+	o.Locked = 0
+}
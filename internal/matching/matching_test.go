@@ -0,0 +1,51 @@
+package matching
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_Account(t *testing.T) {
+	a := NewAccount(map[string]float64{"clp": 10000})
+	o := &Order{Market: "ETHCLP", Base: "ETH", Quote: "CLP", Type: "buy", Status: "active", Price: 10000, Original: 1, Remaining: 1}
+
+	if a.Lock(o, 0.01) {
+		t.Errorf("Expected insufficient funds for 10100 CLP")
+	}
+	o.Original, o.Remaining = 0.5, 0.5
+	if !a.Lock(o, 0.01) || o.Locked != 5050 {
+		t.Errorf("Expected 5050 CLP locked, got %v", o.Locked)
+	}
+
+	if o.Crosses(10100) || !o.Crosses(9900) {
+		t.Errorf("Expected buy at 10000 to cross only lower prices")
+	}
+	a.Fill(o, 0.2, 9900, 0.01, "2017-09-01T14:00:00")
+	if o.Status != "active" || math.Abs(o.Remaining-0.3) > 1e-9 || o.AvgExecutionPrice != 9900 {
+		t.Errorf("Expected 0.3 remaining at 9900, got %+v", o)
+	}
+	a.Fill(o, 0.3, 10000, 0.01, "2017-09-01T14:01:00")
+	if o.Status != "executed" || o.ExecutedAt != "2017-09-01T14:01:00" || o.AvgExecutionPrice != 9960 {
+		t.Errorf("Expected order executed at an average of 9960, got %+v", o)
+	}
+
+	expected := 10000 - (1980+3000)*1.01
+	if o.Locked != 0 || a.Locked["CLP"] != 0 || math.Abs(a.Available["CLP"]-expected) > 1e-6 {
+		t.Errorf("Expected %v CLP available and nothing locked, got %v and %v", expected, a.Available["CLP"], a.Locked["CLP"])
+	}
+	if a.Balance("ETH") != 0.5 || math.Abs(a.Fees-49.8) > 1e-9 || a.Volume != 4980 {
+		t.Errorf("Expected 0.5 ETH, 49.8 fees and 4980 volume, got %v, %v and %v", a.Balance("ETH"), a.Fees, a.Volume)
+	}
+
+	s := &Order{Market: "ETHCLP", Base: "ETH", Quote: "CLP", Type: "sell", Status: "active", Price: 11000, Original: 0.5, Remaining: 0.5}
+	if !a.Lock(s, 0) {
+		t.Errorf("Expected 0.5 ETH to be locked")
+	}
+	a.Cancel(s, "2017-09-01T14:02:00")
+	if s.Status != "cancelled" || a.Available["ETH"] != 0.5 || a.Locked["ETH"] != 0 {
+		t.Errorf("Expected cancelled order to release its funds, got %+v", a)
+	}
+	if w := a.Wallets(); len(w) != 2 || w[0] != "CLP" || w[1] != "ETH" {
+		t.Errorf("Expected wallets CLP and ETH, got %v", w)
+	}
+}
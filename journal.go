@@ -0,0 +1,126 @@
+package cryptomkt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OrderJournal maps client order IDs to the IDs CryptoMarket assigned to
+// those orders. The API has no native client order IDs, so the library keeps
+// this mapping itself.
+type OrderJournal interface {
+	// OrderID returns the server ID of a client order ID.
+	OrderID(clientOrderID string) (string, bool)
+	// ClientOrderID returns the client order ID of a server ID.
+	ClientOrderID(orderID string) (string, bool)
+	// Record stores the mapping between a client order ID and a server ID.
+	Record(clientOrderID, orderID string) error
+}
+
+// MemoryJournal is an OrderJournal kept in memory.
+type MemoryJournal struct {
+	mu       sync.RWMutex
+	byClient map[string]string
+	byOrder  map[string]string
+}
+
+// NewMemoryJournal returns an empty MemoryJournal.
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{
+		byClient: make(map[string]string),
+		byOrder:  make(map[string]string),
+	}
+}
+
+// OrderID implements OrderJournal interface.
+func (mj *MemoryJournal) OrderID(clientOrderID string) (string, bool) {
+	mj.mu.RLock()
+	defer mj.mu.RUnlock()
+	id, ok := mj.byClient[clientOrderID]
+	return id, ok
+}
+
+// ClientOrderID implements OrderJournal interface.
+func (mj *MemoryJournal) ClientOrderID(orderID string) (string, bool) {
+	mj.mu.RLock()
+	defer mj.mu.RUnlock()
+	id, ok := mj.byOrder[orderID]
+	return id, ok
+}
+
+// Record implements OrderJournal interface.
+func (mj *MemoryJournal) Record(clientOrderID, orderID string) error {
+	mj.mu.Lock()
+	defer mj.mu.Unlock()
+	mj.byClient[clientOrderID] = orderID
+	mj.byOrder[orderID] = clientOrderID
+	return nil
+}
+
+// journalEntry represents a line of a FileJournal.
+type journalEntry struct {
+	ClientOrderID string `json:"client_order_id"`
+	OrderID       string `json:"order_id"`
+}
+
+// FileJournal is an OrderJournal persisted as JSON lines, so mappings
+// survive restarts.
+type FileJournal struct {
+	*MemoryJournal
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileJournal opens or creates the journal at path and loads its
+// mappings.
+func NewFileJournal(path string) (*FileJournal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	mj := NewMemoryJournal()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cryptopay: corrupted order journal %s, %v", path, err)
+		}
+		mj.Record(e.ClientOrderID, e.OrderID)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &FileJournal{MemoryJournal: mj, file: f}, nil
+}
+
+// Record implements OrderJournal interface.
+func (fj *FileJournal) Record(clientOrderID, orderID string) error {
+	b, err := json.Marshal(journalEntry{ClientOrderID: clientOrderID, OrderID: orderID})
+	if err != nil {
+		return err
+	}
+
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	if _, err := fj.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := fj.file.Sync(); err != nil {
+		return err
+	}
+
+	return fj.MemoryJournal.Record(clientOrderID, orderID)
+}
+
+// Close closes the underlying file.
+func (fj *FileJournal) Close() error {
+	return fj.file.Close()
+}
@@ -0,0 +1,90 @@
+// Package metrics exposes the requests of a cryptomkt client as Prometheus
+// metrics.
+//
+//	collector := metrics.NewCollector("cryptomkt")
+//	prometheus.MustRegister(collector)
+//	client := cryptomkt.NewClient(key, secret, cryptomkt.WithInstrumentation(collector))
+package metrics
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+var labels = []string{"endpoint", "method", "status"}
+
+// Collector records the requests of a client. It implements both
+// cryptomkt.Instrumentation and prometheus.Collector.
+type Collector struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+var (
+	_ cryptomkt.Instrumentation = (*Collector)(nil)
+	_ prometheus.Collector      = (*Collector)(nil)
+)
+
+// NewCollector returns a collector whose metrics are prefixed by namespace:
+// requests_total, errors_total and request_duration_seconds, labeled by
+// endpoint, method and status. status is the HTTP status code, or "none"
+// when no response was received.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests sent to the CryptoMarket API.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Requests to the CryptoMarket API that returned an error.",
+		}, labels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the requests to the CryptoMarket API.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.errors.Describe(ch)
+	c.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.errors.Collect(ch)
+	c.latency.Collect(ch)
+}
+
+// StartRequest implements cryptomkt.Instrumentation interface.
+func (c *Collector) StartRequest(ctx context.Context, info *cryptomkt.RequestInfo) context.Context {
+	return ctx
+}
+
+// EndRequest implements cryptomkt.Instrumentation interface.
+func (c *Collector) EndRequest(ctx context.Context, info *cryptomkt.RequestInfo) {
+	status := "none"
+	if info.StatusCode != 0 {
+		status = strconv.Itoa(info.StatusCode)
+	}
+	c.requests.WithLabelValues(info.Endpoint, info.Method, status).Inc()
+	if info.Err != nil {
+		c.errors.WithLabelValues(info.Endpoint, info.Method, status).Inc()
+	}
+	if info.StatusCode != 0 {
+		c.latency.WithLabelValues(info.Endpoint, info.Method, status).Observe(info.Duration.Seconds())
+	}
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+func Test_Collector(t *testing.T) {
+	c := NewCollector("cryptomkt")
+	c.EndRequest(context.Background(), &cryptomkt.RequestInfo{Endpoint: "/ticker", Method: "GET", StatusCode: 200, Duration: 20 * time.Millisecond})
+	c.EndRequest(context.Background(), &cryptomkt.RequestInfo{Endpoint: "/ticker", Method: "GET", StatusCode: 200, Duration: 30 * time.Millisecond})
+	c.EndRequest(context.Background(), &cryptomkt.RequestInfo{Endpoint: "/orders", Method: "POST", StatusCode: 400, Duration: time.Millisecond, Err: errors.New("invalid")})
+	c.EndRequest(context.Background(), &cryptomkt.RequestInfo{Endpoint: "/orders", Method: "POST", Err: errors.New("connection reset")})
+
+	expected := `
+# HELP cryptomkt_errors_total Requests to the CryptoMarket API that returned an error.
+# TYPE cryptomkt_errors_total counter
+cryptomkt_errors_total{endpoint="/orders",method="POST",status="400"} 1
+cryptomkt_errors_total{endpoint="/orders",method="POST",status="none"} 1
+# HELP cryptomkt_requests_total Requests sent to the CryptoMarket API.
+# TYPE cryptomkt_requests_total counter
+cryptomkt_requests_total{endpoint="/orders",method="POST",status="400"} 1
+cryptomkt_requests_total{endpoint="/orders",method="POST",status="none"} 1
+cryptomkt_requests_total{endpoint="/ticker",method="GET",status="200"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "cryptomkt_requests_total", "cryptomkt_errors_total"); err != nil {
+		t.Errorf("Unexpected metrics: %v", err)
+	}
+	if n := testutil.CollectAndCount(c, "cryptomkt_request_duration_seconds"); n != 2 {
+		t.Errorf("Expected 2 latency histograms, got %d", n)
+	}
+}
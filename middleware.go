@@ -0,0 +1,204 @@
+package cryptomkt
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Operation names the API operation of a request.
+type Operation string
+
+// Operations of the API.
+const (
+	OperationGetMarkets        Operation = "GetMarkets"
+	OperationGetTicker         Operation = "GetTicker"
+	OperationGetOrdersBook     Operation = "GetOrdersBook"
+	OperationGetTrades         Operation = "GetTrades"
+	OperationGetPrices         Operation = "GetPrices"
+	OperationGetActiveOrders   Operation = "GetActiveOrders"
+	OperationGetExecutedOrders Operation = "GetExecutedOrders"
+	OperationCreateOrder       Operation = "CreateOrder"
+	OperationGetOrderStatus    Operation = "GetOrderStatus"
+	OperationCancelOrder       Operation = "CancelOrder"
+	OperationGetBalance        Operation = "GetBalance"
+	OperationGetSocketAuth     Operation = "GetSocketAuth"
+	OperationGetTransactions   Operation = "GetTransactions"
+	OperationTransfer          Operation = "Transfer"
+	OperationNotifyDeposit     Operation = "NotifyDeposit"
+	OperationNotifyWithdrawal  Operation = "NotifyWithdrawal"
+	OperationCreatePayment     Operation = "CreatePayment"
+	OperationPaymentStatus     Operation = "PaymentStatus"
+	OperationPaymentOrders     Operation = "PaymentOrders"
+)
+
+// operations maps the method and endpoint of a request to its operation.
+var operations = map[string]Operation{
+	"GET /market":              OperationGetMarkets,
+	"GET /ticker":              OperationGetTicker,
+	"GET /book":                OperationGetOrdersBook,
+	"GET /trades":              OperationGetTrades,
+	"GET /prices":              OperationGetPrices,
+	"GET /orders/active":       OperationGetActiveOrders,
+	"GET /orders/executed":     OperationGetExecutedOrders,
+	"POST /orders":             OperationCreateOrder,
+	"GET /orders/status":       OperationGetOrderStatus,
+	"POST /orders/cancel":      OperationCancelOrder,
+	"GET /balance":             OperationGetBalance,
+	"GET /socket/auth":         OperationGetSocketAuth,
+	"GET /transactions":        OperationGetTransactions,
+	"POST /transfer":           OperationTransfer,
+	"POST /request/deposit":    OperationNotifyDeposit,
+	"POST /request/withdrawal": OperationNotifyWithdrawal,
+	"POST /payment/new_order":  OperationCreatePayment,
+	"GET /payment/status":      OperationPaymentStatus,
+	"GET /payment/orders":      OperationPaymentOrders,
+}
+
+// responseTypes returns, by operation, a new value of the type its
+// successful responses are decoded into.
+var responseTypes = map[Operation]func() interface{}{
+	OperationGetMarkets:        func() interface{} { return new(MarketResponse) },
+	OperationGetTicker:         func() interface{} { return new(TickerResponse) },
+	OperationGetOrdersBook:     func() interface{} { return new(BooksResponse) },
+	OperationGetTrades:         func() interface{} { return new(TradesResponse) },
+	OperationGetPrices:         func() interface{} { return new(PricesResponse) },
+	OperationGetActiveOrders:   func() interface{} { return new(MarketOrdersResponse) },
+	OperationGetExecutedOrders: func() interface{} { return new(MarketOrdersResponse) },
+	OperationCreateOrder:       func() interface{} { return new(MarketOrderResponse) },
+	OperationGetOrderStatus:    func() interface{} { return new(MarketOrderResponse) },
+	OperationCancelOrder:       func() interface{} { return new(MarketOrderResponse) },
+	OperationGetBalance:        func() interface{} { return new(BalanceResponse) },
+	OperationGetSocketAuth:     func() interface{} { return new(SocketAuthResponse) },
+	OperationGetTransactions:   func() interface{} { return new(TransactionsResponse) },
+	OperationTransfer:          func() interface{} { return new(TransferResponse) },
+	OperationNotifyDeposit:     func() interface{} { return new(NotificationResponse) },
+	OperationNotifyWithdrawal:  func() interface{} { return new(NotificationResponse) },
+	OperationCreatePayment:     func() interface{} { return new(Response) },
+	OperationPaymentStatus:     func() interface{} { return new(Response) },
+	OperationPaymentOrders:     func() interface{} { return new(PaymentOrdersResponse) },
+}
+
+// operationOf returns the operation of a request, or method and endpoint
+// for requests the library does not know.
+func operationOf(method, endpoint string) Operation {
+	if op, ok := operations[method+" "+endpoint]; ok {
+		return op
+	}
+	return Operation(method + " " + endpoint)
+}
+
+// Request represents a request about to be sent to the API.
+type Request struct {
+	Operation Operation
+	// HTTP is the request, signed when private. Changing the parameters
+	// after signing invalidates the signature.
+	HTTP *http.Request
+	// Values are the signed parameters of the request.
+	Values url.Values
+}
+
+// Result represents a response of the API.
+type Result struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the whole body of the response.
+	Body []byte
+	// Value is the decoded response, a pointer to the type of the operation
+	// such as *TickerResponse or *MarketOrderResponse. It is nil for error
+	// responses, bodies that do not decode and unknown operations. Services
+	// return Value when set and decode Body otherwise: a middleware that
+	// replaces Body must replace or clear Value too.
+	Value interface{}
+}
+
+// Decode decodes the JSON body of the response into v.
+func (r *Result) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Handler sends a request to the API. The result is nil when no response
+// was received. Successful responses carry their decoded Value, error
+// responses are returned with their result and an *APIError.
+type Handler func(req *Request) (*Result, error)
+
+// Middleware wraps the sending of requests. Middlewares see requests after
+// the rate limiter and the signature, and responses once decoded, before
+// the services return them. Responses served from the cache do not go
+// through them.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds mws around the requests of the client. The first
+// middleware given, by this or previous options, is the outermost: it sees
+// the request first and the result last.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *Client) {
+		for _, hc := range c.httpClients() {
+			hc.middlewares = append(hc.middlewares, mws...)
+		}
+	}
+}
+
+// LoggingMiddleware logs every request with its status, latency and error.
+// Requests are logged to the standard error when logger is nil; the
+// standard logger is not used, NewClient discards its output.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	return func(next Handler) Handler {
+		return func(req *Request) (*Result, error) {
+			start := time.Now()
+			res, err := next(req)
+			status := 0
+			if res != nil {
+				status = res.StatusCode
+			}
+			if err != nil {
+				logger.Printf("cryptomkt: %s %s %d %v: %v", req.Operation, endpoint(req.HTTP.URL.Path), status, time.Since(start), err)
+			} else {
+				logger.Printf("cryptomkt: %s %s %d %v", req.Operation, endpoint(req.HTTP.URL.Path), status, time.Since(start))
+			}
+			return res, err
+		}
+	}
+}
+
+// InstrumentationMiddleware reports every request to inst. It is what
+// WithInstrumentation adds.
+func InstrumentationMiddleware(inst Instrumentation) Middleware {
+	return func(next Handler) Handler {
+		return func(req *Request) (*Result, error) {
+			info := &RequestInfo{
+				Operation: req.Operation,
+				Endpoint:  endpoint(req.HTTP.URL.Path),
+				Method:    req.HTTP.Method,
+			}
+			ctx := inst.StartRequest(req.HTTP.Context(), info)
+			r := *req
+			r.HTTP = req.HTTP.WithContext(ctx)
+
+			start := time.Now()
+			res, err := next(&r)
+			info.Duration = time.Since(start)
+			if res != nil {
+				info.StatusCode = res.StatusCode
+			}
+			info.Err = err
+			inst.EndRequest(ctx, info)
+			return res, err
+		}
+	}
+}
+
+// handler returns the chain of middlewares around send.
+func (hc *httpClient) handler() Handler {
+	h := hc.send
+	for i := len(hc.middlewares) - 1; i >= 0; i-- {
+		h = hc.middlewares[i](h)
+	}
+	return h
+}
@@ -0,0 +1,120 @@
+package cryptomkt
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_WithMiddleware(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Audit") != "trader-1" {
+			t.Errorf("Expected injected header, got %q", r.Header.Get("X-Audit"))
+		}
+		switch r.URL.Path {
+		case "/v1/balance":
+			w.Write(getBalanceResponse)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": "error", "message": "invalid_market"}`))
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	var events []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *Request) (*Result, error) {
+				events = append(events, name+" > "+string(req.Operation))
+				if req.HTTP.Header.Get(headerXMktSignature) == "" {
+					t.Errorf("Expected signed request")
+				}
+				res, err := next(req)
+				events = append(events, name+" < "+string(req.Operation))
+				if err != nil {
+					if _, ok := err.(*APIError); !ok || res == nil || res.StatusCode != http.StatusBadRequest {
+						t.Errorf("Expected API error with its result, got %v", err)
+					}
+				}
+				return res, err
+			}
+		}
+	}
+	audit := func(next Handler) Handler {
+		return func(req *Request) (*Result, error) {
+			req.HTTP.Header.Set("X-Audit", "trader-1")
+			res, err := next(req)
+			if err == nil {
+				br, ok := res.Value.(*BalanceResponse)
+				if !ok || len(br.Data) == 0 {
+					t.Errorf("Expected decoded balance, got %#v", res.Value)
+				}
+			} else if res != nil && res.Value != nil {
+				t.Errorf("Expected no value for error responses, got %#v", res.Value)
+			}
+			return res, err
+		}
+	}
+
+	var logs bytes.Buffer
+	c := NewClient("some-key", "some-secret",
+		WithHTTPClient(httpCli),
+		WithMiddleware(trace("outer"), LoggingMiddleware(log.New(&logs, "", 0))),
+		WithMiddleware(trace("inner"), audit),
+	)
+
+	if _, err := c.GetBalance(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := c.GetActiveOrders(&MarketOrderOptions{Market: "XXX"}); err == nil {
+		t.Errorf("Expected invalid market error")
+	}
+
+	expected := []string{
+		"outer > GetBalance", "inner > GetBalance", "inner < GetBalance", "outer < GetBalance",
+		"outer > GetActiveOrders", "inner > GetActiveOrders", "inner < GetActiveOrders", "outer < GetActiveOrders",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v, got %v", expected, events)
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "cryptomkt: GetBalance /balance 200") ||
+		!strings.HasSuffix(lines[1], "cryptopay: invalid_market") {
+		t.Errorf("Unexpected logs %q", logs.String())
+	}
+}
+
+func Test_MiddlewareValue(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(getBalanceResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	// The services return the decoded value instead of decoding the body
+	// again.
+	hide := func(next Handler) Handler {
+		return func(req *Request) (*Result, error) {
+			res, err := next(req)
+			if br, ok := res.Value.(*BalanceResponse); ok {
+				br.Data = br.Data[:1]
+			}
+			return res, err
+		}
+	}
+	c := NewClient("some-key", "some-secret", WithHTTPClient(httpCli), WithMiddleware(hide))
+
+	br, err := c.GetBalance()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(br.Data) != 1 || br.Data[0].Wallet != "CLP" {
+		t.Errorf("Expected the balance of the middleware, got %+v", br.Data)
+	}
+}
@@ -0,0 +1,264 @@
+package cryptomkt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInsufficientDepth is returned when the book does not hold enough volume
+// to fill an amount.
+var ErrInsufficientDepth = errors.New("cryptopay: insufficient book depth")
+
+// PriceLevel represents the volume available at a price.
+type PriceLevel struct {
+	Price  float64
+	Amount float64
+}
+
+// OrderBook keeps both sides of the book of a market aggregated by price.
+// The buy side is sorted by descending price and the sell side by ascending
+// price, so the best price always comes first. It is safe for concurrent use.
+type OrderBook struct {
+	Market string
+
+	mu   sync.RWMutex
+	buy  []PriceLevel
+	sell []PriceLevel
+}
+
+// NewOrderBook returns an empty book of market.
+func NewOrderBook(market string) *OrderBook {
+	return &OrderBook{Market: strings.ToUpper(market)}
+}
+
+// GetFullOrderBook walks every page of both sides of the book of market.
+func (ps *PublicService) GetFullOrderBook(market string) (*OrderBook, error) {
+	ob := NewOrderBook(market)
+	for _, side := range []string{"buy", "sell"} {
+		opts := &BooksOptions{Market: market, Type: side, Limit: 100}
+		levels := make([]*Book, 0)
+		for {
+			br, err := ps.GetOrdersBook(opts)
+			if err != nil {
+				return nil, err
+			}
+			levels = append(levels, br.Data...)
+			if br.Pagination == nil || int(br.Pagination.Next) <= opts.Page {
+				break
+			}
+			opts.Page = int(br.Pagination.Next)
+		}
+		if err := ob.SetSide(side, levels); err != nil {
+			return nil, err
+		}
+	}
+	return ob, nil
+}
+
+// parseLevels aggregates books by price.
+func parseLevels(books []*Book) (map[float64]float64, error) {
+	levels := make(map[float64]float64, len(books))
+	for _, b := range books {
+		price, err := strconv.ParseFloat(b.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cryptopay: invalid book price %q", b.Price)
+		}
+		amount, err := strconv.ParseFloat(b.Amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cryptopay: invalid book amount %q", b.Amount)
+		}
+		levels[price] += amount
+	}
+	return levels, nil
+}
+
+// sortLevels returns levels sorted best price first.
+func sortLevels(side string, levels map[float64]float64) []PriceLevel {
+	sorted := make([]PriceLevel, 0, len(levels))
+	for price, amount := range levels {
+		if amount > 0 {
+			sorted = append(sorted, PriceLevel{Price: price, Amount: amount})
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if side == "buy" {
+			return sorted[i].Price > sorted[j].Price
+		}
+		return sorted[i].Price < sorted[j].Price
+	})
+	return sorted
+}
+
+// side returns a pointer to the levels of side, buy or sell.
+func (ob *OrderBook) side(side string) (*[]PriceLevel, error) {
+	switch side {
+	case "buy":
+		return &ob.buy, nil
+	case "sell":
+		return &ob.sell, nil
+	default:
+		return nil, fmt.Errorf("cryptopay: invalid book side %q", side)
+	}
+}
+
+// SetSide replaces every level of side, buy or sell, for example with a page
+// of GetOrdersBook or a socket snapshot.
+func (ob *OrderBook) SetSide(side string, books []*Book) error {
+	levels, err := parseLevels(books)
+	if err != nil {
+		return err
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	s, err := ob.side(side)
+	if err != nil {
+		return err
+	}
+	*s = sortLevels(side, levels)
+	return nil
+}
+
+// Update applies a diff to side, buy or sell. Each book replaces the amount
+// of its price level, a zero amount removes the level.
+func (ob *OrderBook) Update(side string, books []*Book) error {
+	diff, err := parseLevels(books)
+	if err != nil {
+		return err
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	s, err := ob.side(side)
+	if err != nil {
+		return err
+	}
+	levels := make(map[float64]float64, len(*s)+len(diff))
+	for _, l := range *s {
+		levels[l.Price] = l.Amount
+	}
+	for price, amount := range diff {
+		levels[price] = amount
+	}
+	*s = sortLevels(side, levels)
+	return nil
+}
+
+// Levels returns a copy of the levels of side, buy or sell, best price first.
+func (ob *OrderBook) Levels(side string) []PriceLevel {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	s, err := ob.side(side)
+	if err != nil {
+		return nil
+	}
+	levels := make([]PriceLevel, len(*s))
+	copy(levels, *s)
+	return levels
+}
+
+// BestBid returns the highest buy level.
+func (ob *OrderBook) BestBid() (PriceLevel, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	if len(ob.buy) == 0 {
+		return PriceLevel{}, false
+	}
+	return ob.buy[0], true
+}
+
+// BestAsk returns the lowest sell level.
+func (ob *OrderBook) BestAsk() (PriceLevel, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	if len(ob.sell) == 0 {
+		return PriceLevel{}, false
+	}
+	return ob.sell[0], true
+}
+
+// Spread returns the difference between the best ask and the best bid.
+func (ob *OrderBook) Spread() (float64, bool) {
+	bid, ok := ob.BestBid()
+	if !ok {
+		return 0, false
+	}
+	ask, ok := ob.BestAsk()
+	if !ok {
+		return 0, false
+	}
+	return ask.Price - bid.Price, true
+}
+
+// DepthTo returns the volume of side, buy or sell, at prices as good as or
+// better than price: at or above it for buy, at or below it for sell.
+func (ob *OrderBook) DepthTo(side string, price float64) float64 {
+	depth := 0.0
+	for _, l := range ob.Levels(side) {
+		if (side == "buy" && l.Price < price) || (side == "sell" && l.Price > price) {
+			break
+		}
+		depth += l.Amount
+	}
+	return depth
+}
+
+// Cumulative returns the levels of side, buy or sell, with the amount of each
+// level accumulated with the better ones.
+func (ob *OrderBook) Cumulative(side string) []PriceLevel {
+	levels := ob.Levels(side)
+	total := 0.0
+	for i := range levels {
+		total += levels[i].Amount
+		levels[i].Amount = total
+	}
+	return levels
+}
+
+// AveragePrice returns the average price paid to fill amount with a market
+// order of the given type: buy orders consume the sell side and sell orders
+// the buy side. ErrInsufficientDepth is returned when the book is too thin.
+func (ob *OrderBook) AveragePrice(orderType string, amount float64) (float64, error) {
+	if amount <= 0 {
+		return 0, errors.New("cryptopay: amount must be positive")
+	}
+
+	side := "sell"
+	if orderType == "sell" {
+		side = "buy"
+	}
+
+	remaining, cost := amount, 0.0
+	for _, l := range ob.Levels(side) {
+		fill := math.Min(remaining, l.Amount)
+		cost += fill * l.Price
+		remaining -= fill
+		if remaining <= 0 {
+			return cost / amount, nil
+		}
+	}
+	return 0, ErrInsufficientDepth
+}
+
+// Slippage returns the relative distance between the average fill price of
+// amount and the best price, for example 0.01 for 1%.
+func (ob *OrderBook) Slippage(orderType string, amount float64) (float64, error) {
+	avg, err := ob.AveragePrice(orderType, amount)
+	if err != nil {
+		return 0, err
+	}
+
+	best, ok := ob.BestAsk()
+	if orderType == "sell" {
+		best, ok = ob.BestBid()
+	}
+	if !ok {
+		return 0, ErrInsufficientDepth
+	}
+	return math.Abs(avg-best.Price) / best.Price, nil
+}
@@ -0,0 +1,101 @@
+package cryptomkt
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+func Test_GetFullOrderBook(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("type") == "buy" && q.Get("page") == "1":
+			w.Write(getBuyBookLastPageResponse)
+		case q.Get("type") == "buy":
+			w.Write(getBuyBookResponse)
+		default:
+			w.Write(getSellBookResponse)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	ob, err := ps.GetFullOrderBook("ETHCLP")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	if bids := ob.Levels("buy"); len(bids) != 3 || bids[0].Price != 9950 || bids[1].Amount != 1.5 {
+		t.Errorf("Expected 3 aggregated buy levels, got %+v", bids)
+	}
+
+	spread, ok := ob.Spread()
+	if !ok || spread != 50 {
+		t.Errorf("Expected spread 50, got %v", spread)
+	}
+
+	if depth := ob.DepthTo("sell", 10100); depth != 3 {
+		t.Errorf("Expected sell depth 3, got %v", depth)
+	}
+
+	cum := ob.Cumulative("buy")
+	if cum[2].Amount != 3.5 {
+		t.Errorf("Expected cumulative buy volume 3.5, got %v", cum[2].Amount)
+	}
+
+	avg, err := ob.AveragePrice("buy", 2)
+	if err != nil || avg != 10050 {
+		t.Errorf("Expected average price 10050, got %v, %v", avg, err)
+	}
+
+	slippage, err := ob.Slippage("buy", 2)
+	if err != nil || math.Abs(slippage-0.05/10) > 1e-9 {
+		t.Errorf("Expected slippage 0.5%%, got %v, %v", slippage, err)
+	}
+
+	if _, err := ob.AveragePrice("sell", 10); err != ErrInsufficientDepth {
+		t.Errorf("Expected error %v, got %v", ErrInsufficientDepth, err)
+	}
+
+	ob.Update("sell", []*Book{{Price: "10000", Amount: "0"}, {Price: "9990", Amount: "0.5"}})
+	if ask, _ := ob.BestAsk(); ask.Price != 9990 {
+		t.Errorf("Expected best ask 9990, got %v", ask.Price)
+	}
+}
+
+var getBuyBookResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {"previous": "null", "limit": 100, "page": 0, "next": 1},
+		"data": [
+		   {"price": "9950", "amount": "1", "timestamp": "2017-09-01T14:01:56.887272"},
+		   {"price": "9900", "amount": "1", "timestamp": "2017-09-01T14:01:56.887272"},
+		   {"price": "9900", "amount": "0.5", "timestamp": "2017-09-01T14:01:56.887272"}
+		]
+	}
+`)
+
+var getBuyBookLastPageResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {"previous": 0, "limit": 100, "page": 1, "next": "null"},
+		"data": [
+		   {"price": "9800", "amount": "1", "timestamp": "2017-09-01T14:01:56.887272"}
+		]
+	}
+`)
+
+var getSellBookResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {"previous": "null", "limit": 100, "page": 0, "next": "null"},
+		"data": [
+		   {"price": "10100", "amount": "2", "timestamp": "2017-09-01T14:01:56.887272"},
+		   {"price": "10000", "amount": "1", "timestamp": "2017-09-01T14:01:56.887272"},
+		   {"price": "10200", "amount": "5", "timestamp": "2017-09-01T14:01:56.887272"}
+		]
+	}
+`)
@@ -0,0 +1,475 @@
+package cryptomkt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Finciero/go-cryptomkt/internal/matching"
+)
+
+// MarketData provides the books and trades paper orders are filled against.
+type MarketData interface {
+	// OrderBook returns the levels of side, buy or sell, of market.
+	OrderBook(market, side string) ([]*Book, error)
+	// Trades returns the latest trades of market. Trades already seen are
+	// ignored, so they may be returned again.
+	Trades(market string) ([]*Trade, error)
+}
+
+// LiveMarketData is a MarketData backed by the public endpoints.
+type LiveMarketData struct {
+	ps *PublicService
+}
+
+// NewLiveMarketData returns a MarketData using ps.
+func NewLiveMarketData(ps *PublicService) *LiveMarketData {
+	return &LiveMarketData{ps: ps}
+}
+
+// OrderBook implements MarketData interface.
+func (lm *LiveMarketData) OrderBook(market, side string) ([]*Book, error) {
+	br, err := lm.ps.GetOrdersBook(&BooksOptions{Market: market, Type: side, Limit: 100})
+	if err != nil {
+		return nil, err
+	}
+	return br.Data, nil
+}
+
+// Trades implements MarketData interface.
+func (lm *LiveMarketData) Trades(market string) ([]*Trade, error) {
+	tr, err := lm.ps.GetTrades(&TradesOptions{Market: market, Limit: 100})
+	if err != nil {
+		return nil, err
+	}
+	return tr.Data, nil
+}
+
+// RecordedMarketData is a MarketData replaying books and trades set by the
+// caller, for example from a backfill. It is safe for concurrent use.
+type RecordedMarketData struct {
+	mu     sync.Mutex
+	books  map[string][]*Book
+	trades map[string][]*Trade
+}
+
+// NewRecordedMarketData returns an empty RecordedMarketData.
+func NewRecordedMarketData() *RecordedMarketData {
+	return &RecordedMarketData{
+		books:  make(map[string][]*Book),
+		trades: make(map[string][]*Trade),
+	}
+}
+
+// SetBook replaces the levels of side, buy or sell, of market.
+func (rm *RecordedMarketData) SetBook(market, side string, books []*Book) {
+	rm.mu.Lock()
+	rm.books[strings.ToUpper(market)+"/"+side] = books
+	rm.mu.Unlock()
+}
+
+// AddTrades appends trades of market.
+func (rm *RecordedMarketData) AddTrades(market string, trades ...*Trade) {
+	rm.mu.Lock()
+	market = strings.ToUpper(market)
+	rm.trades[market] = append(rm.trades[market], trades...)
+	rm.mu.Unlock()
+}
+
+// OrderBook implements MarketData interface.
+func (rm *RecordedMarketData) OrderBook(market, side string) ([]*Book, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.books[strings.ToUpper(market)+"/"+side], nil
+}
+
+// Trades implements MarketData interface.
+func (rm *RecordedMarketData) Trades(market string) ([]*Trade, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	trades := rm.trades[strings.ToUpper(market)]
+	return append([]*Trade(nil), trades...), nil
+}
+
+// PaperOptions configures a PaperTrader.
+type PaperOptions struct {
+	// Balances holds the initial balance of every currency.
+	Balances map[string]float64
+	// MakerFee is the fee rate of orders filled while resting in the book.
+	MakerFee float64
+	// TakerFee is the fee rate of orders filled when created.
+	TakerFee float64
+	// Data is the source of books and trades. The public endpoints are used
+	// when nil.
+	Data MarketData
+}
+
+// PaperTrader simulates the trading endpoints of PrivateService. Orders are
+// filled as takers against the book when created, and as makers against
+// the trades seen while they rest. Fees are charged in the quote currency.
+// Other private requests that move funds are rejected, read-only ones are
+// sent to the API.
+type PaperTrader struct {
+	makerFee float64
+	takerFee float64
+
+	mu     sync.Mutex
+	data   MarketData
+	next   http.RoundTripper
+	acct   *matching.Account
+	orders []*matching.Order
+	seen   map[string]*tradeCursor
+	lastID int
+}
+
+// tradeCursor records the trades of a market already matched. Trades are
+// matched in timestamp order, so only the last timestamp and the trades at
+// that timestamp are kept.
+type tradeCursor struct {
+	last string
+	ids  map[string]bool
+}
+
+// add records t and reports whether it was not seen before.
+func (tc *tradeCursor) add(t *Trade) bool {
+	switch {
+	case t.Timestamp < tc.last:
+		return false
+	case t.Timestamp == tc.last:
+		if tc.ids[t.Tid] {
+			return false
+		}
+		tc.ids[t.Tid] = true
+	default:
+		tc.last = t.Timestamp
+		tc.ids = map[string]bool{t.Tid: true}
+	}
+	return true
+}
+
+// NewPaperTrader returns a PaperTrader configured with opts.
+func NewPaperTrader(opts *PaperOptions) *PaperTrader {
+	return &PaperTrader{
+		makerFee: opts.MakerFee,
+		takerFee: opts.TakerFee,
+		data:     opts.Data,
+		acct:     matching.NewAccount(opts.Balances),
+		seen:     make(map[string]*tradeCursor),
+	}
+}
+
+// attach makes pt the transport of hc. Requests not simulated are sent with
+// the previous transport, which also serves the live market data.
+func (pt *PaperTrader) attach(hc *httpClient) {
+	pt.next = hc.client.Transport
+	if pt.next == nil {
+		pt.next = http.DefaultTransport
+	}
+	if pt.data == nil {
+		pt.data = NewLiveMarketData(&PublicService{client: &httpClient{client: hc.client, limiter: hc.limiter}})
+	}
+	hc.client = &http.Client{Transport: pt, Timeout: hc.client.Timeout}
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (pt *PaperTrader) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/v1")
+	q := req.URL.Query()
+
+	var form url.Values
+	if req.Method == http.MethodPost {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if form, err = url.ParseQuery(string(body)); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch {
+	case req.Method == http.MethodGet && path == "/balance":
+		v = pt.balances()
+	case req.Method == http.MethodGet && (path == "/orders/active" || path == "/orders/executed"):
+		if err := pt.Sync(); err != nil {
+			return nil, err
+		}
+		v = pt.list(q.Get("market"), strings.TrimPrefix(path, "/orders/"))
+	case req.Method == http.MethodGet && path == "/orders/status":
+		if err := pt.Sync(); err != nil {
+			return nil, err
+		}
+		v, err = pt.status(q.Get("id"))
+	case req.Method == http.MethodGet:
+		return pt.next.RoundTrip(req)
+	case path == "/orders":
+		if err := pt.Sync(); err != nil {
+			return nil, err
+		}
+		v, err = pt.create(form)
+		if _, ok := err.(*APIError); err != nil && !ok {
+			return nil, err
+		}
+	case path == "/orders/cancel":
+		if err := pt.Sync(); err != nil {
+			return nil, err
+		}
+		v, err = pt.cancel(form.Get("id"))
+	default:
+		err = paperError("not available in paper trading")
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		status, v = http.StatusBadRequest, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(b)),
+		Request:    req,
+	}, nil
+}
+
+// paperError returns an *APIError with message.
+func paperError(message string) error {
+	return &APIError{Status: "error", Message: message}
+}
+
+// Balances returns the simulated balance of every currency.
+func (pt *PaperTrader) Balances() []*Balance {
+	return pt.balances().Data
+}
+
+func (pt *PaperTrader) balances() *BalanceResponse {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	wallets := pt.acct.Wallets()
+	data := make([]*Balance, len(wallets))
+	for i, c := range wallets {
+		data[i] = &Balance{Wallet: c, Available: pt.acct.Available[c], Balance: pt.acct.Balance(c)}
+	}
+	return &BalanceResponse{Status: "success", Data: data}
+}
+
+func (pt *PaperTrader) list(market, status string) *MarketOrdersResponse {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	data := make([]*MarketOrder, 0)
+	for _, o := range pt.orders {
+		if o.Status == status && strings.EqualFold(o.Market, market) {
+			data = append(data, paperMarketOrder(o))
+		}
+	}
+	return &MarketOrdersResponse{Status: "success", Data: data, Pagination: &Pagination{Limit: len(data)}}
+}
+
+func (pt *PaperTrader) status(id string) (*MarketOrderResponse, error) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	o := pt.find(id)
+	if o == nil {
+		return nil, paperError("order not found")
+	}
+	return &MarketOrderResponse{Status: "success", Data: paperMarketOrder(o)}, nil
+}
+
+func (pt *PaperTrader) find(id string) *matching.Order {
+	for _, o := range pt.orders {
+		if o.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+func (pt *PaperTrader) create(form url.Values) (*MarketOrderResponse, error) {
+	market := strings.ToUpper(form.Get("market"))
+	base, quote, err := SplitMarket(market)
+	if err != nil {
+		return nil, paperError("invalid market")
+	}
+	typ := form.Get("type")
+	if typ != "buy" && typ != "sell" {
+		return nil, paperError("invalid type")
+	}
+	amount, err := strconv.ParseFloat(form.Get("amount"), 64)
+	if err != nil || amount <= 0 {
+		return nil, paperError("invalid amount")
+	}
+	price, err := strconv.ParseInt(form.Get("price"), 10, 64)
+	if err != nil || price <= 0 {
+		return nil, paperError("invalid price")
+	}
+
+	// Fetch the market data before locking, the data may be remote.
+	side := "sell"
+	if typ == "sell" {
+		side = "buy"
+	}
+	books, err := pt.data.OrderBook(market, side)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := parseLevels(books)
+	if err != nil {
+		return nil, err
+	}
+	trades, err := pt.data.Trades(market)
+	if err != nil {
+		return nil, err
+	}
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	// Trades that happened before the order fill the resting orders only.
+	pt.matchTrades(market, trades)
+
+	o := &matching.Order{
+		Market:    market,
+		Base:      base,
+		Quote:     quote,
+		Type:      typ,
+		Status:    "active",
+		Price:     price,
+		Original:  amount,
+		Remaining: amount,
+		CreatedAt: time.Now().UTC().Format(dateLayout),
+	}
+	if !pt.acct.Lock(o, math.Max(pt.makerFee, pt.takerFee)) {
+		return nil, paperError("insufficient_funds")
+	}
+
+	pt.lastID++
+	o.ID = fmt.Sprintf("P%d", pt.lastID)
+	o.UpdatedAt = o.CreatedAt
+	pt.orders = append(pt.orders, o)
+
+	for _, l := range sortLevels(side, levels) {
+		if o.Status != "active" || !o.Crosses(l.Price) {
+			break
+		}
+		pt.acct.Fill(o, math.Min(o.Remaining, l.Amount), l.Price, pt.takerFee, time.Now().UTC().Format(dateLayout))
+	}
+
+	return &MarketOrderResponse{Status: "success", Data: paperMarketOrder(o)}, nil
+}
+
+func (pt *PaperTrader) cancel(id string) (*MarketOrderResponse, error) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	o := pt.find(id)
+	if o == nil {
+		return nil, paperError("order not found")
+	}
+	if o.Status != "active" {
+		return nil, paperError("order is not active")
+	}
+	pt.acct.Cancel(o, time.Now().UTC().Format(dateLayout))
+	return &MarketOrderResponse{Status: "success", Data: paperMarketOrder(o)}, nil
+}
+
+// Sync fills the resting orders against the trades not seen yet. It is
+// called before every simulated order request.
+func (pt *PaperTrader) Sync() error {
+	pt.mu.Lock()
+	markets := make(map[string]bool)
+	for _, o := range pt.orders {
+		if o.Status == "active" {
+			markets[o.Market] = true
+		}
+	}
+	pt.mu.Unlock()
+
+	for market := range markets {
+		trades, err := pt.data.Trades(market)
+		if err != nil {
+			return err
+		}
+		pt.mu.Lock()
+		pt.matchTrades(market, trades)
+		pt.mu.Unlock()
+	}
+	return nil
+}
+
+// matchTrades fills the active orders of market against the trades not seen
+// yet, oldest first. pt.mu must be held.
+func (pt *PaperTrader) matchTrades(market string, trades []*Trade) {
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Timestamp < trades[j].Timestamp })
+	tc := pt.seen[market]
+	if tc == nil {
+		tc = &tradeCursor{}
+		pt.seen[market] = tc
+	}
+	for _, t := range trades {
+		if tc.add(t) {
+			pt.match(market, t)
+		}
+	}
+}
+
+// match fills the active orders of market crossed by t, oldest first, at
+// their own price.
+func (pt *PaperTrader) match(market string, t *Trade) {
+	price, err := strconv.ParseFloat(t.Price, 64)
+	if err != nil {
+		return
+	}
+	left, err := strconv.ParseFloat(t.Amount, 64)
+	if err != nil {
+		return
+	}
+	for _, o := range pt.orders {
+		if left <= 0 {
+			return
+		}
+		if o.Status != "active" || o.Market != market || !o.Crosses(price) {
+			continue
+		}
+		qty := math.Min(o.Remaining, left)
+		pt.acct.Fill(o, qty, float64(o.Price), pt.makerFee, time.Now().UTC().Format(dateLayout))
+		left -= qty
+	}
+}
+
+// paperMarketOrder returns o as returned by the API.
+func paperMarketOrder(o *matching.Order) *MarketOrder {
+	return &MarketOrder{
+		ID:                o.ID,
+		Status:            o.Status,
+		Type:              o.Type,
+		Price:             o.Price,
+		Amount:            &OrderAmount{Original: o.Original, Remaining: o.Remaining, Executed: o.Executed},
+		ExecutionPrice:    o.ExecutionPrice,
+		AvgExecutionPrice: o.AvgExecutionPrice,
+		Market:            o.Market,
+		CreatedAt:         o.CreatedAt,
+		UpdatedAt:         o.UpdatedAt,
+		ExecutedAt:        o.ExecutedAt,
+	}
+}
@@ -0,0 +1,174 @@
+package cryptomkt
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func Test_PaperTrading(t *testing.T) {
+	data := NewRecordedMarketData()
+	data.SetBook("ETHCLP", "sell", []*Book{{Price: "10100", Amount: "0.5"}, {Price: "10200", Amount: "1"}})
+	data.SetBook("ETHCLP", "buy", []*Book{{Price: "10050", Amount: "1"}})
+	data.AddTrades("ETHCLP", &Trade{Tid: "1", Price: "10000", Amount: "5", Timestamp: "2017-09-01T14:00:00.000000"})
+
+	pt := NewPaperTrader(&PaperOptions{
+		Balances: map[string]float64{"CLP": 1000000},
+		MakerFee: 0.005,
+		TakerFee: 0.01,
+		Data:     data,
+	})
+	c := NewClient("some-key", "some-secret", WithPaperTrading(pt))
+
+	// Half of the order is filled against the book, the rest rests.
+	morr, err := c.CreateOrder(&MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1, Price: 10150})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	id := morr.Data.ID
+	if morr.Data.Status != "active" || morr.Data.Amount.Remaining != 0.5 || morr.Data.AvgExecutionPrice != 10100 {
+		t.Errorf("Expected order to rest with 0.5 remaining, got %+v %+v", morr.Data, morr.Data.Amount)
+	}
+
+	// A new trade at a better price fills the resting order as maker. The
+	// trade recorded before the order is ignored.
+	data.AddTrades("ETHCLP", &Trade{Tid: "2", Price: "10140", Amount: "0.3", Timestamp: "2017-09-01T14:01:00.000000"})
+	mor, err := c.GetActiveOrders(&MarketOrderOptions{Market: "ETHCLP"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(mor.Data) != 1 || math.Abs(mor.Data[0].Amount.Remaining-0.2) > 1e-9 {
+		t.Errorf("Expected 0.2 remaining, got %+v", mor.Data)
+	}
+
+	if _, err := c.CancelOrder(&CancelOrderRequest{ID: id}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expectBalance(t, c, "CLP", 1000000-5050*1.01-3045*1.005)
+	expectBalance(t, c, "ETH", 0.8)
+
+	// A sell filled at once against the buy side.
+	morr, err = c.CreateOrder(&MarketOrderRequest{Market: "ETHCLP", Type: "sell", Amount: 0.8, Price: 10000})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if morr.Data.Status != "executed" {
+		t.Errorf("Expected order executed, got %s", morr.Data.Status)
+	}
+	executed, err := c.GetExecutedOrders(&MarketOrderOptions{Market: "ETHCLP"})
+	if err != nil || len(executed.Data) != 1 {
+		t.Errorf("Expected 1 executed order, got %v %v", executed, err)
+	}
+	expectBalance(t, c, "ETH", 0)
+
+	_, err = c.CreateOrder(&MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1000, Price: 10000})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "insufficient_funds" {
+		t.Errorf("Expected insufficient funds error, got %v", err)
+	}
+
+	if _, err := c.Transfer(&TransferRequest{Currency: "ETH", Address: "0x52908400098527886e0f7030069857d2e4169ee7", Amount: 1}); err == nil {
+		t.Errorf("Expected transfers to be rejected in paper trading")
+	}
+}
+
+func Test_PaperTradingOptionOrder(t *testing.T) {
+	requests := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	data := NewRecordedMarketData()
+	data.SetBook("ETHCLP", "sell", []*Book{{Price: "10100", Amount: "1"}})
+	pt := NewPaperTrader(&PaperOptions{Balances: map[string]float64{"CLP": 1000000}, Data: data})
+	c := NewClient("some-key", "some-secret", WithPaperTrading(pt), WithHTTPClient(httpCli))
+
+	if _, err := c.CreateOrder(&MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1, Price: 10100}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected order to be simulated, got %d live requests", requests)
+	}
+	expectBalance(t, c, "ETH", 1)
+}
+
+// lateMarketData records a trade right after the first trades are fetched,
+// as if it happened between two requests.
+type lateMarketData struct {
+	*RecordedMarketData
+	late *Trade
+}
+
+func (lm *lateMarketData) Trades(market string) ([]*Trade, error) {
+	trades, err := lm.RecordedMarketData.Trades(market)
+	if lm.late != nil {
+		lm.AddTrades(market, lm.late)
+		lm.late = nil
+	}
+	return trades, err
+}
+
+func Test_PaperTradingRestingFill(t *testing.T) {
+	data := &lateMarketData{RecordedMarketData: NewRecordedMarketData()}
+	data.AddTrades("ETHCLP", &Trade{Tid: "1", Price: "10000", Amount: "5", Timestamp: "2017-09-01T14:00:00.000000"})
+	pt := NewPaperTrader(&PaperOptions{Balances: map[string]float64{"CLP": 1000000}, Data: data})
+	c := NewClient("some-key", "some-secret", WithPaperTrading(pt))
+
+	resting, err := c.CreateOrder(&MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1, Price: 9900})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	// The trade arrives after the sync of the next order, it still fills
+	// the resting order but not the new one.
+	data.late = &Trade{Tid: "2", Price: "9800", Amount: "0.4", Timestamp: "2017-09-01T14:01:00.000000"}
+	morr, err := c.CreateOrder(&MarketOrderRequest{Market: "ETHCLP", Type: "buy", Amount: 1, Price: 9850})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if morr.Data.Amount.Remaining != 1 {
+		t.Errorf("Expected new order unfilled, got %+v", morr.Data.Amount)
+	}
+	osr, err := c.GetOrderStatus(&OrderStatusOption{ID: resting.Data.ID})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if math.Abs(osr.Data.Amount.Remaining-0.6) > 1e-9 {
+		t.Errorf("Expected 0.6 remaining on the resting order, got %+v", osr.Data.Amount)
+	}
+
+	// Trades already matched are not matched again.
+	if err := pt.Sync(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expectBalance(t, c, "ETH", 0.4)
+}
+
+func expectBalance(t *testing.T, c *Client, wallet string, expected float64) {
+	t.Helper()
+	br, err := c.GetBalance()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	for _, b := range br.Data {
+		if b.Wallet == wallet {
+			if math.Abs(b.Balance-expected) > 1e-6 {
+				t.Errorf("Expected %s balance %v, got %v", wallet, expected, b.Balance)
+			}
+			return
+		}
+	}
+	if expected != 0 {
+		t.Errorf("Expected %s balance %v, got none", wallet, expected)
+	}
+}
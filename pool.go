@@ -0,0 +1,172 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AccountPool holds the clients of several accounts of one process, keyed
+// by account name. Accounts share one HTTP client, the public service and
+// the options given to NewAccountPool, such as WithCache or WithMiddleware.
+// Credentials and the options given to Add, such as WithRateLimit or
+// WithRiskManager, are kept per account.
+type AccountPool struct {
+	opts   []Option
+	public *Client
+
+	mu       sync.RWMutex
+	accounts map[string]*Client
+}
+
+// NewAccountPool returns an empty pool. opts are applied to the public
+// service and to every account. Options bound to one account, such as
+// WithCredentials, WithOrderJournal, WithRiskManager or WithPaperTrading,
+// are rejected: pass them to Add.
+func NewAccountPool(opts ...Option) (*AccountPool, error) {
+	opts = append([]Option{WithHTTPClient(&http.Client{})}, opts...)
+	public := NewPublicClient(append([]Option{func(c *Client) { c.pool = true }}, opts...)...)
+	if public.err != nil {
+		return nil, public.err
+	}
+	return &AccountPool{
+		opts:     opts,
+		public:   public,
+		accounts: make(map[string]*Client),
+	}, nil
+}
+
+// Add registers the account name signed with the credentials of creds and
+// returns its client. opts apply to this account only.
+func (ap *AccountPool) Add(name string, creds CredentialsProvider, opts ...Option) (*Client, error) {
+	if name == "" {
+		return nil, errors.New("cryptopay: account name is required")
+	}
+
+	all := append(append([]Option{}, ap.opts...), WithCredentials(creds))
+	c := NewClient("", "", append(all, opts...)...)
+	c.PublicService = ap.public.PublicService
+
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	if _, ok := ap.accounts[name]; ok {
+		return nil, fmt.Errorf("cryptopay: account %q already exists", name)
+	}
+	ap.accounts[name] = c
+	return c, nil
+}
+
+// Account returns the client of the account name, or nil when there is no
+// such account.
+func (ap *AccountPool) Account(name string) *Client {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+	return ap.accounts[name]
+}
+
+// Names returns the names of the accounts, sorted.
+func (ap *AccountPool) Names() []string {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+	names := make([]string, 0, len(ap.accounts))
+	for name := range ap.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Public returns the public service shared by the accounts.
+func (ap *AccountPool) Public() *PublicService {
+	return &ap.public.PublicService
+}
+
+// Remove unregisters the account name and zeroes its credentials.
+func (ap *AccountPool) Remove(name string) error {
+	ap.mu.Lock()
+	c, ok := ap.accounts[name]
+	delete(ap.accounts, name)
+	ap.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("cryptopay: unknown account %q", name)
+	}
+	return c.Close()
+}
+
+// Close zeroes the credentials of every account.
+func (ap *AccountPool) Close() error {
+	var err error
+	for _, name := range ap.Names() {
+		if rerr := ap.Remove(name); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// PoolBalance represents the balances of the accounts of a pool.
+type PoolBalance struct {
+	// Accounts holds the balances of every account that answered.
+	Accounts map[string][]*Balance
+	// Totals holds the balances summed over the accounts, by wallet.
+	Totals map[string]*Balance
+	// Errors holds the error of every account that failed.
+	Errors map[string]error
+}
+
+// Balances fetches the balance of every account concurrently and sums them
+// by wallet. Failed accounts are left out of the totals and are also
+// aggregated in the returned *BatchError.
+func (ap *AccountPool) Balances(ctx context.Context) (*PoolBalance, error) {
+	names := ap.Names()
+	pb := &PoolBalance{
+		Accounts: make(map[string][]*Balance, len(names)),
+		Totals:   make(map[string]*Balance),
+		Errors:   make(map[string]error),
+	}
+
+	var mu sync.Mutex
+	runWorkers(ctx, len(names), func(i int, err error) {
+		var br *BalanceResponse
+		if err == nil {
+			if c := ap.Account(names[i]); c != nil {
+				br, err = c.GetBalance()
+			} else {
+				err = fmt.Errorf("cryptopay: unknown account %q", names[i])
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			pb.Errors[names[i]] = fmt.Errorf("cryptopay: account %s: %v", names[i], err)
+			return
+		}
+		pb.Accounts[names[i]] = br.Data
+	})
+
+	var errs []error
+	for _, name := range names {
+		if err, ok := pb.Errors[name]; ok {
+			errs = append(errs, err)
+			continue
+		}
+		for _, b := range pb.Accounts[name] {
+			wallet := strings.ToUpper(b.Wallet)
+			total, ok := pb.Totals[wallet]
+			if !ok {
+				total = &Balance{Wallet: wallet}
+				pb.Totals[wallet] = total
+			}
+			total.Available += b.Available
+			total.Balance += b.Balance
+		}
+	}
+	if len(errs) > 0 {
+		return pb, &BatchError{Errors: errs}
+	}
+	return pb, nil
+}
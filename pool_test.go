@@ -0,0 +1,102 @@
+package cryptomkt
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_AccountPool(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get(headerXMktAPIKey) {
+		case "treasury-key", "mm-key":
+			w.Write(getBalanceResponse)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status": "error", "message": "invalid_key"}`))
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rc := NewResponseCache(nil)
+	ap, err := NewAccountPool(WithHTTPClient(httpCli), WithCache(rc))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	treasury, err := ap.Add("treasury", NewStaticProvider("treasury-key", "treasury-secret"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	mm, _ := ap.Add("market-making", NewStaticProvider("mm-key", "mm-secret"), WithRateLimit(10, time.Second))
+	ap.Add("merchant", NewStaticProvider("revoked-key", "revoked-secret"))
+	if _, err := ap.Add("treasury", NewStaticProvider("other-key", "other-secret")); err == nil {
+		t.Errorf("Expected duplicated account error")
+	}
+
+	if treasury.PublicService.client != ap.Public().client || mm.PublicService.client != ap.Public().client {
+		t.Errorf("Expected accounts to share the public service")
+	}
+	if treasury.PrivateService.client.client != httpCli || ap.Public().client.cache != rc {
+		t.Errorf("Expected accounts to share the HTTP client and the cache")
+	}
+	if treasury.PrivateService.client.limiter != nil || mm.PrivateService.client.limiter == nil {
+		t.Errorf("Expected rate limit of market-making only")
+	}
+
+	pb, err := ap.Balances(context.Background())
+	batchErr, ok := err.(*BatchError)
+	if !ok || len(batchErr.Errors) != 1 {
+		t.Errorf("Expected merchant error, got %v", err)
+		return
+	}
+	if _, ok := pb.Errors["merchant"]; !ok || len(pb.Accounts) != 2 {
+		t.Errorf("Expected 2 balances and merchant error, got %v and %v", pb.Accounts, pb.Errors)
+	}
+	if eth := pb.Totals["ETH"]; eth == nil || eth.Balance != 2*11.3399 || eth.Available != 2*10.3399 {
+		t.Errorf("Expected ETH total of both accounts, got %+v", eth)
+	}
+
+	if err := ap.Remove("merchant"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if ap.Account("merchant") != nil || len(ap.Names()) != 2 {
+		t.Errorf("Expected merchant removed, got %v", ap.Names())
+	}
+	if err := ap.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := treasury.GetBalance(); err != errCredentialsClosed {
+		t.Errorf("Expected closed credentials error, got %v", err)
+	}
+}
+
+func Test_AccountPoolAccountOptions(t *testing.T) {
+	rm := NewRiskManager(RiskLimits{})
+	pt := NewPaperTrader(&PaperOptions{})
+	for _, opt := range []Option{WithRiskManager(rm), WithPaperTrading(pt), WithOrderJournal(NewMemoryJournal()), WithCredentials(NewStaticProvider("key", "secret"))} {
+		if _, err := NewAccountPool(WithCache(NewResponseCache(nil)), opt); err == nil {
+			t.Errorf("Expected per-account option to be rejected")
+		}
+	}
+	if rm.ps != nil {
+		t.Errorf("Expected risk manager not to be bound to the pool")
+	}
+
+	ap, err := NewAccountPool()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	c, err := ap.Add("market-making", NewStaticProvider("mm-key", "mm-secret"), WithRiskManager(rm))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if rm.ps != &c.PrivateService {
+		t.Errorf("Expected risk manager bound to its account")
+	}
+}
@@ -0,0 +1,206 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Holding represents one currency of a portfolio valued in the reference
+// currency.
+type Holding struct {
+	Currency string
+	// Amount is the balance of the wallet, amounts locked in active orders
+	// included.
+	Amount float64
+	Locked float64
+	// Price is the last price of one unit in the reference currency, zero
+	// when the currency could not be priced.
+	Price float64
+	Value float64
+	// Route lists the markets the price goes through, empty for the
+	// reference currency.
+	Route []string
+	// Allocation is the percentage of the total value of the portfolio.
+	Allocation float64
+	// CostBasis is the cost of the holding in the reference currency, zero
+	// when unknown.
+	CostBasis     float64
+	UnrealizedPnL float64
+}
+
+// Portfolio represents balances valued in a reference currency.
+type Portfolio struct {
+	Currency string
+	// Holdings are sorted by value, largest first.
+	Holdings      []*Holding
+	Total         float64
+	CostBasis     float64
+	UnrealizedPnL float64
+	// Unpriced lists the currencies with a balance but no route to the
+	// reference currency. They are left out of the total.
+	Unpriced []string
+}
+
+// PortfolioOptions represents the options of a portfolio valuation.
+type PortfolioOptions struct {
+	// Currency is the reference currency, for example CLP, ARS, BRL or EUR.
+	Currency string
+	// CostBasis holds the cost of the holdings of every currency in the
+	// reference currency. Unrealized PnL is computed for these currencies
+	// only.
+	CostBasis map[string]float64
+}
+
+// GetPortfolio values the balances of the account with the current tickers.
+func (c *Client) GetPortfolio(ctx context.Context, opts *PortfolioOptions) (*Portfolio, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("cryptopay: portfolio options are required")
+	}
+	br, err := c.getBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Clients made by NewClient send public requests with the private client.
+	ps := &c.PublicService
+	if ps.client == nil {
+		ps = &PublicService{client: c.PrivateService.client}
+	}
+	// Tickers that fail to parse leave their currencies unpriced.
+	results, err := ps.GetTickers(ctx)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return nil, err
+	}
+	tickers := make([]*Ticker, 0, len(results))
+	for _, r := range results {
+		if r.Err == nil && r.Ticker != nil {
+			tickers = append(tickers, r.Ticker)
+		}
+	}
+	return NewPortfolio(br.Data, tickers, opts)
+}
+
+// NewPortfolio values balances with the last prices of tickers. Currencies
+// without a market in the reference currency are priced through the
+// shortest chain of markets, for example XLM in EUR through XLMCLP, ETHCLP
+// and ETHEUR.
+func NewPortfolio(balances []*Balance, tickers []*Ticker, opts *PortfolioOptions) (*Portfolio, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("cryptopay: portfolio options are required")
+	}
+	ref := strings.ToUpper(opts.Currency)
+	if ref == "" {
+		return nil, fmt.Errorf("cryptopay: reference currency is required")
+	}
+	rates := newRateGraph(tickers)
+
+	p := &Portfolio{Currency: ref}
+	for _, b := range balances {
+		if b.Balance == 0 {
+			continue
+		}
+		h := &Holding{
+			Currency: strings.ToUpper(b.Wallet),
+			Amount:   b.Balance,
+			Locked:   b.Balance - b.Available,
+		}
+		price, route, ok := rates.price(h.Currency, ref)
+		if !ok {
+			p.Unpriced = append(p.Unpriced, h.Currency)
+			continue
+		}
+		h.Price, h.Route = price, route
+		h.Value = h.Amount * price
+		if cost, ok := opts.CostBasis[h.Currency]; ok {
+			h.CostBasis = cost
+			h.UnrealizedPnL = h.Value - cost
+			p.CostBasis += cost
+			p.UnrealizedPnL += h.UnrealizedPnL
+		}
+		p.Total += h.Value
+		p.Holdings = append(p.Holdings, h)
+	}
+
+	for _, h := range p.Holdings {
+		if p.Total != 0 {
+			h.Allocation = h.Value / p.Total * 100
+		}
+	}
+	sort.SliceStable(p.Holdings, func(i, j int) bool { return p.Holdings[i].Value > p.Holdings[j].Value })
+	sort.Strings(p.Unpriced)
+	return p, nil
+}
+
+// rateEdge converts one unit of a currency into another through a market.
+type rateEdge struct {
+	to     string
+	rate   float64
+	market string
+}
+
+// rateGraph holds the conversions between currencies given by the markets.
+type rateGraph map[string][]rateEdge
+
+// newRateGraph returns the graph of the markets of tickers. Tickers without
+// a valid last price are skipped, so their currencies may end up unpriced.
+func newRateGraph(tickers []*Ticker) rateGraph {
+	g := make(rateGraph)
+	for _, t := range tickers {
+		base, quote, err := SplitMarket(t.Market)
+		if err != nil {
+			continue
+		}
+		ts, err := NewTickerSnapshot(t)
+		if err != nil || ts.Last <= 0 {
+			continue
+		}
+		market := strings.ToUpper(t.Market)
+		g[base] = append(g[base], rateEdge{to: quote, rate: ts.Last, market: market})
+		g[quote] = append(g[quote], rateEdge{to: base, rate: 1 / ts.Last, market: market})
+	}
+	// Markets are walked in a stable order so routes do not change between
+	// runs.
+	for _, edges := range g {
+		sort.Slice(edges, func(i, j int) bool { return edges[i].market < edges[j].market })
+	}
+	return g
+}
+
+// price returns the price of one unit of from in to through the route with
+// the fewest markets.
+func (g rateGraph) price(from, to string) (float64, []string, bool) {
+	if from == to {
+		return 1, nil, true
+	}
+
+	type step struct {
+		currency string
+		rate     float64
+		route    []string
+	}
+	seen := map[string]bool{from: true}
+	queue := []step{{currency: from, rate: 1}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, e := range g[s.currency] {
+			if seen[e.to] {
+				continue
+			}
+			seen[e.to] = true
+			next := step{
+				currency: e.to,
+				rate:     s.rate * e.rate,
+				route:    append(append([]string(nil), s.route...), e.market),
+			}
+			if e.to == to {
+				return next.rate, next.route, true
+			}
+			queue = append(queue, next)
+		}
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,116 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_NewPortfolio(t *testing.T) {
+	tickers := []*Ticker{
+		{Market: "ETHCLP", Bid: "199000", Ask: "201000", LastPrice: "200000", High: "0", Low: "0", Volume: "0"},
+		{Market: "XLMCLP", Bid: "99", Ask: "101", LastPrice: "100", High: "0", Low: "0", Volume: "0"},
+		{Market: "ETHEUR", Bid: "249", Ask: "251", LastPrice: "250", High: "0", Low: "0", Volume: "0"},
+		// An invalid ticker leaves its currency unpriced.
+		{Market: "DOGECLP", Bid: "1", Ask: "2", LastPrice: "n/a", High: "0", Low: "0", Volume: "0"},
+	}
+	balances := []*Balance{
+		{Wallet: "CLP", Available: 100000, Balance: 200000},
+		{Wallet: "ETH", Available: 1, Balance: 1},
+		{Wallet: "XLM", Available: 1000, Balance: 1000},
+		{Wallet: "DOGE", Available: 5, Balance: 5},
+		{Wallet: "BTC"},
+	}
+
+	p, err := NewPortfolio(balances, tickers, &PortfolioOptions{
+		Currency:  "eur",
+		CostBasis: map[string]float64{"ETH": 200},
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	// 1 EUR = 800 CLP through ETH.
+	expected := map[string]struct {
+		value, locked float64
+		route         []string
+	}{
+		"ETH": {250, 0, []string{"ETHEUR"}},
+		"CLP": {250, 100000, []string{"ETHCLP", "ETHEUR"}},
+		"XLM": {125, 0, []string{"XLMCLP", "ETHCLP", "ETHEUR"}},
+	}
+	if len(p.Holdings) != len(expected) {
+		t.Errorf("Expected %d holdings, got %d", len(expected), len(p.Holdings))
+		return
+	}
+	for _, h := range p.Holdings {
+		e := expected[h.Currency]
+		if math.Abs(h.Value-e.value) > 1e-9 || h.Locked != e.locked || !reflect.DeepEqual(h.Route, e.route) {
+			t.Errorf("Expected %s worth %v through %v, got %v through %v", h.Currency, e.value, e.route, h.Value, h.Route)
+		}
+	}
+	if p.Holdings[2].Currency != "XLM" || math.Abs(p.Holdings[2].Allocation-20) > 1e-9 {
+		t.Errorf("Expected XLM last with 20%%, got %s with %v", p.Holdings[2].Currency, p.Holdings[2].Allocation)
+	}
+	if math.Abs(p.Total-625) > 1e-9 || p.CostBasis != 200 || math.Abs(p.UnrealizedPnL-50) > 1e-9 {
+		t.Errorf("Unexpected totals %v, %v, %v", p.Total, p.CostBasis, p.UnrealizedPnL)
+	}
+	if !reflect.DeepEqual(p.Unpriced, []string{"DOGE"}) {
+		t.Errorf("Expected DOGE unpriced, got %v", p.Unpriced)
+	}
+}
+
+func Test_GetPortfolio(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/balance":
+			w.Write(getBalanceResponse)
+		case "/v1/ticker":
+			w.Write(getTickersResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	c := NewClient("some-key", "some-secret", WithHTTPClient(httpCli))
+	p, err := c.GetPortfolio(context.Background(), &PortfolioOptions{Currency: "CLP"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(p.Holdings) == 0 || p.Total <= 0 {
+		t.Errorf("Expected valued holdings, got %+v", p)
+	}
+}
+
+func Test_GetPortfolioOptions(t *testing.T) {
+	requests := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	c := NewClient("some-key", "some-secret", WithHTTPClient(httpCli))
+
+	if _, err := NewPortfolio(nil, nil, nil); err == nil {
+		t.Errorf("Expected error without options")
+	}
+	if _, err := c.GetPortfolio(context.Background(), nil); err == nil {
+		t.Errorf("Expected error without options")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetPortfolio(ctx, &PortfolioOptions{Currency: "CLP"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests, got %d", requests)
+	}
+}
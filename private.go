@@ -1,6 +1,7 @@
 package cryptomkt
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
@@ -11,6 +12,14 @@ import (
 type PrivateService struct {
 	client  *httpClient
 	Private bool
+
+	allowList map[string]bool
+	journal   OrderJournal
+	validator *OrderValidator
+	risk      *RiskManager
+	// cancels holds the orders cancelled through the client, for
+	// CreateOrderIdempotent.
+	cancels *cancelLog
 }
 
 // OrderAmount represent an Amount in MakerOrder
@@ -68,13 +77,17 @@ type MarketOrderOptions struct {
 
 // GetActiveOrders return a collection of active orders.
 func (ps *PrivateService) GetActiveOrders(opts *MarketOrderOptions) (*MarketOrdersResponse, error) {
+	return ps.getActiveOrders(context.Background(), opts)
+}
+
+func (ps *PrivateService) getActiveOrders(ctx context.Context, opts *MarketOrderOptions) (*MarketOrdersResponse, error) {
 	ps.client.SetPrivate(ps.Private)
 	v, err := query.Values(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := ps.client.get(fmt.Sprintf("/orders/active?%s", v.Encode()), nil)
+	resp, err := ps.client.getContext(ctx, fmt.Sprintf("/orders/active?%s", v.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -114,6 +127,9 @@ type MarketOrderRequest struct {
 	Amount float64 `json:"amount,string,omitempty"`
 	Price  int     `json:"price,string,omitempty"`
 	Type   string  `json:"type,omitempty"`
+	// ClientOrderID is an optional identifier chosen by the caller. It is
+	// not sent to the API, the client keeps it in its OrderJournal.
+	ClientOrderID string `json:"-"`
 }
 
 // Params returns a map used to sign the requests
@@ -136,16 +152,54 @@ type MarketOrderResponse struct {
 
 // CreateOrder creates a new order.
 func (ps *PrivateService) CreateOrder(mor *MarketOrderRequest) (*MarketOrderResponse, error) {
+	return ps.createOrder(context.Background(), mor)
+}
+
+func (ps *PrivateService) createOrder(ctx context.Context, mor *MarketOrderRequest) (*MarketOrderResponse, error) {
+	// Errors raised before sending mean the order was not created, even
+	// when a request made to check it failed.
+	if ps.validator != nil {
+		if err := ps.validator.Validate(mor); err != nil {
+			return nil, notSent(err)
+		}
+	}
+	// settle gives back the share of the risk limits reserved for mor.
+	settle := func(*MarketOrder, error) {}
+	if ps.risk != nil {
+		res, err := ps.risk.reserve(ctx, mor)
+		if err != nil {
+			if ps.validator != nil {
+				ps.validator.Release(mor)
+			}
+			return nil, notSent(err)
+		}
+		settle = func(o *MarketOrder, err error) { ps.risk.settle(res, o, err) }
+	}
+
 	ps.client.SetPrivate(ps.Private)
-	resp, err := ps.client.postForm("/orders", mor.Params())
+	resp, err := ps.client.postFormContext(ctx, "/orders", mor.Params())
 	if err != nil {
+		settle(nil, err)
+		if ps.validator != nil && !isAmbiguous(err) {
+			ps.validator.Release(mor)
+		}
 		return nil, err
 	}
 
 	var morr MarketOrderResponse
 	if err := unmarshalJSON(resp.Body, &morr); err != nil {
+		// The API answered but not with an order: it may have created it.
+		err = &TransportError{Err: err}
+		settle(nil, err)
 		return nil, err
 	}
+	settle(morr.Data, nil)
+
+	if mor.ClientOrderID != "" && ps.journal != nil && morr.Data != nil {
+		if err := ps.journal.Record(mor.ClientOrderID, morr.Data.ID); err != nil {
+			return &morr, err
+		}
+	}
 
 	return &morr, nil
 }
@@ -191,8 +245,12 @@ func (cor *CancelOrderRequest) Params() url.Values {
 
 // CancelOrder cancel an order.
 func (ps *PrivateService) CancelOrder(mor *CancelOrderRequest) (*MarketOrderResponse, error) {
+	return ps.cancelOrder(context.Background(), mor)
+}
+
+func (ps *PrivateService) cancelOrder(ctx context.Context, mor *CancelOrderRequest) (*MarketOrderResponse, error) {
 	ps.client.SetPrivate(ps.Private)
-	resp, err := ps.client.postForm("/orders/cancel", mor.Params())
+	resp, err := ps.client.postFormContext(ctx, "/orders/cancel", mor.Params())
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +259,9 @@ func (ps *PrivateService) CancelOrder(mor *CancelOrderRequest) (*MarketOrderResp
 	if err := unmarshalJSON(resp.Body, &morr); err != nil {
 		return nil, err
 	}
+	if ps.cancels != nil && morr.Data != nil {
+		ps.cancels.add(morr.Data)
+	}
 
 	return &morr, nil
 }
@@ -220,8 +281,12 @@ type BalanceResponse struct {
 
 // GetBalance returns balance from wallets of the given key.
 func (ps *PrivateService) GetBalance() (*BalanceResponse, error) {
+	return ps.getBalance(context.Background())
+}
+
+func (ps *PrivateService) getBalance(ctx context.Context) (*BalanceResponse, error) {
 	ps.client.SetPrivate(ps.Private)
-	resp, err := ps.client.get("/balance", nil)
+	resp, err := ps.client.getContext(ctx, "/balance", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -233,3 +298,35 @@ func (ps *PrivateService) GetBalance() (*BalanceResponse, error) {
 
 	return &br, nil
 }
+
+// SocketAuth represents the credentials used to authenticate a socket
+// connection.
+type SocketAuth struct {
+	// ID de usuario
+	UID string `json:"uid,omitempty"`
+	// ID de socket
+	SocID string `json:"socid,omitempty"`
+}
+
+// SocketAuthResponse represents a socket auth response.
+type SocketAuthResponse struct {
+	Status string      `json:"status,omitempty"`
+	Data   *SocketAuth `json:"data,omitempty"`
+}
+
+// GetSocketAuth returns the credentials needed to open an authenticated
+// socket connection.
+func (ps *PrivateService) GetSocketAuth() (*SocketAuthResponse, error) {
+	ps.client.SetPrivate(ps.Private)
+	resp, err := ps.client.get("/socket/auth", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sar SocketAuthResponse
+	if err := unmarshalJSON(resp.Body, &sar); err != nil {
+		return nil, err
+	}
+
+	return &sar, nil
+}
@@ -1,11 +1,13 @@
 package cryptomkt
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -18,8 +20,7 @@ func Test_GetActiveOrders(t *testing.T) {
 	ps := &PrivateService{
 		client: &httpClient{
 			client:  httpCli,
-			key:     "some-key",
-			secret:  "some-secret",
+			creds:   NewStaticProvider("some-key", "some-secret"),
 			private: true,
 		},
 		Private: true,
@@ -76,8 +77,7 @@ func Test_GetExecutedOrders(t *testing.T) {
 	ps := &PrivateService{
 		client: &httpClient{
 			client:  httpCli,
-			key:     "some-key",
-			secret:  "some-secret",
+			creds:   NewStaticProvider("some-key", "some-secret"),
 			private: true,
 		},
 		Private: true,
@@ -134,8 +134,7 @@ func Test_CreateOrder(t *testing.T) {
 	ps := &PrivateService{
 		client: &httpClient{
 			client:  httpCli,
-			key:     "some-key",
-			secret:  "some-secret",
+			creds:   NewStaticProvider("some-key", "some-secret"),
 			private: true,
 		},
 		Private: true,
@@ -173,6 +172,31 @@ func Test_CreateOrder(t *testing.T) {
 	}
 }
 
+func Test_CreateOrderDryRun(t *testing.T) {
+	requests := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	var buf bytes.Buffer
+	c := NewClient("some-key", "some-secret", WithHTTPClient(httpCli), WithDryRun(&buf))
+
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy"}
+	if _, err := c.CreateOrder(mor); err != ErrDryRun {
+		t.Errorf("Expected %v, got %v", ErrDryRun, err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests, got %d", requests)
+	}
+	for _, expected := range []string{"POST /v1/orders HTTP/1.1", "X-Mkt-Apikey: some-key", "X-Mkt-Signature: "} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in dry run output, got\n%s", expected, buf.String())
+		}
+	}
+}
+
 func Test_GetOrderStatus(t *testing.T) {
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write(getStatusOrderResponse)
@@ -182,8 +206,7 @@ func Test_GetOrderStatus(t *testing.T) {
 	ps := &PrivateService{
 		client: &httpClient{
 			client:  httpCli,
-			key:     "some-key",
-			secret:  "some-secret",
+			creds:   NewStaticProvider("some-key", "some-secret"),
 			private: true,
 		},
 		Private: true,
@@ -226,8 +249,7 @@ func Test_CancelOrder(t *testing.T) {
 	ps := &PrivateService{
 		client: &httpClient{
 			client:  httpCli,
-			key:     "some-key",
-			secret:  "some-secret",
+			creds:   NewStaticProvider("some-key", "some-secret"),
 			private: true,
 		},
 		Private: true,
@@ -270,8 +292,7 @@ func Test_GetBalance(t *testing.T) {
 	ps := &PrivateService{
 		client: &httpClient{
 			client:  httpCli,
-			key:     "some-key",
-			secret:  "some-secret",
+			creds:   NewStaticProvider("some-key", "some-secret"),
 			private: true,
 		},
 		Private: true,
@@ -290,6 +311,38 @@ func Test_GetBalance(t *testing.T) {
 	}
 }
 
+func Test_GetSocketAuth(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(getSocketAuthResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	sar, err := ps.GetSocketAuth()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	if sar.Data == nil {
+		t.Errorf("Expected Data not be nil")
+		return
+	}
+
+	expectedUID := "244"
+	if sar.Data.UID != expectedUID {
+		t.Errorf("Expected uid to be %s, got %s", expectedUID, sar.Data.UID)
+	}
+}
+
 func testingHTTPClient(handler http.Handler) (*http.Client, func()) {
 	s := httptest.NewTLSServer(handler)
 
@@ -482,3 +535,13 @@ var getCancelOrderResponse = []byte(`
 		}
 	 }
 `)
+
+var getSocketAuthResponse = []byte(`
+	{
+		"status": "success",
+		"data": {
+		   "uid": "244",
+		   "socid": "4fd0c3f6b8d34f0da5c18c1ac6b4d3b1"
+		}
+	 }
+`)
@@ -1,6 +1,7 @@
 package cryptomkt
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/go-querystring/query"
@@ -20,7 +21,11 @@ type MarketResponse struct {
 
 // GetMarkets returns a list of available markets
 func (ps *PublicService) GetMarkets() (*MarketResponse, error) {
-	resp, err := ps.client.get("/market", nil)
+	return ps.getMarkets(context.Background())
+}
+
+func (ps *PublicService) getMarkets(ctx context.Context) (*MarketResponse, error) {
+	resp, err := ps.client.getContext(ctx, "/market", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +47,7 @@ type Ticker struct {
 	LastPrice string `json:"last_price"`
 	Volume    string `json:"volume"`
 	Market    string `json:"market"`
-	Timestamp string `json:"time[[[[stamp"`
+	Timestamp string `json:"timestamp"`
 }
 
 // TickerResponse represent a ticker response.
@@ -53,7 +58,11 @@ type TickerResponse struct {
 
 // GetTicker returns a list of available ticker
 func (ps *PublicService) GetTicker(market string) (*TickerResponse, error) {
-	resp, err := ps.client.get(fmt.Sprintf("/ticker?market=%s", market), nil)
+	return ps.getTicker(context.Background(), fmt.Sprintf("/ticker?market=%s", market))
+}
+
+func (ps *PublicService) getTicker(ctx context.Context, path string) (*TickerResponse, error) {
+	resp, err := ps.client.getContext(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -98,12 +107,16 @@ type BooksOptions struct {
 
 // GetOrdersBook return a collection of active orders.
 func (ps *PublicService) GetOrdersBook(opts *BooksOptions) (*BooksResponse, error) {
+	return ps.getOrdersBook(context.Background(), opts)
+}
+
+func (ps *PublicService) getOrdersBook(ctx context.Context, opts *BooksOptions) (*BooksResponse, error) {
 	v, err := query.Values(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := ps.client.get(fmt.Sprintf("/book?%s", v.Encode()), nil)
+	resp, err := ps.client.getContext(ctx, fmt.Sprintf("/book?%s", v.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -150,12 +163,16 @@ type TradesOptions struct {
 
 // GetTrades return a collection of trades.
 func (ps *PublicService) GetTrades(opts *TradesOptions) (*TradesResponse, error) {
+	return ps.getTrades(context.Background(), opts)
+}
+
+func (ps *PublicService) getTrades(ctx context.Context, opts *TradesOptions) (*TradesResponse, error) {
 	v, err := query.Values(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := ps.client.get(fmt.Sprintf("/trades?%s", v.Encode()), nil)
+	resp, err := ps.client.getContext(ctx, fmt.Sprintf("/trades?%s", v.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,47 @@
+package cryptomkt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests evenly so no more than n are sent per period.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(n int, per time.Duration) *rateLimiter {
+	if n <= 0 {
+		n = 1
+	}
+	return &rateLimiter{interval: per / time.Duration(n)}
+}
+
+// wait blocks until the next request slot or until ctx is done.
+func (rl *rateLimiter) wait(ctx context.Context) error {
+	rl.mu.Lock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	slot := rl.next
+	rl.next = rl.next.Add(rl.interval)
+	rl.mu.Unlock()
+
+	d := time.Until(slot)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
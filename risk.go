@@ -0,0 +1,284 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RiskLimit identifies the limit that rejected an order.
+type RiskLimit int
+
+// Limits enforced by RiskManager.
+const (
+	RiskKillSwitch RiskLimit = iota + 1
+	RiskOrderNotional
+	RiskOpenOrders
+	RiskDailyVolume
+	RiskPriceBand
+)
+
+// String returns a human readable limit name.
+func (rl RiskLimit) String() string {
+	switch rl {
+	case RiskKillSwitch:
+		return "kill-switch"
+	case RiskOrderNotional:
+		return "order-notional"
+	case RiskOpenOrders:
+		return "open-orders"
+	case RiskDailyVolume:
+		return "daily-volume"
+	case RiskPriceBand:
+		return "price-band"
+	default:
+		return "unknown"
+	}
+}
+
+// RiskError represents an order rejected by RiskManager.
+type RiskError struct {
+	Limit  RiskLimit
+	Market string
+	Reason string
+}
+
+// Error implements error interface.
+func (err *RiskError) Error() string {
+	return fmt.Sprintf("cryptopay: order rejected by %s limit on %s, %s", err.Limit, err.Market, err.Reason)
+}
+
+// RiskLimits represents the limits applied to new orders. Notional values
+// are expressed in the quote currency of each market. Zero values disable a
+// limit.
+type RiskLimits struct {
+	// MaxOrderNotional is the maximum amount*price of an order, by market.
+	MaxOrderNotional map[string]float64
+	// MaxOpenOrders is the maximum number of active orders of a market.
+	MaxOpenOrders int
+	// MaxDailyVolume is the maximum notional traded per UTC day, by market.
+	MaxDailyVolume map[string]float64
+	// PriceBand is the maximum relative distance between the order price and
+	// the last price of the ticker, for example 0.05 for 5%.
+	PriceBand float64
+}
+
+// errRiskDetached is returned when a RiskManager needs the API but was never
+// passed to WithRiskManager.
+var errRiskDetached = errors.New("cryptopay: risk manager is not attached to a client")
+
+// RiskManager enforces RiskLimits on CreateOrder and provides a kill switch
+// that blocks every new order.
+type RiskManager struct {
+	limits RiskLimits
+	ps     *PrivateService
+
+	mu     sync.Mutex
+	killed bool
+	day    string
+	volume map[string]float64
+	// pending counts the orders of each market being sent.
+	pending map[string]int
+	// created holds the orders of each market sent since the last listing of
+	// active orders, by ID, with the time they were sent. Orders whose ID is
+	// unknown because the request failed ambiguously get a placeholder key.
+	created map[string]map[string]time.Time
+	seq     int
+}
+
+// riskReservation is the share of the limits taken by an order being sent.
+type riskReservation struct {
+	market   string
+	notional float64
+	day      string
+}
+
+// NewRiskManager returns a manager enforcing limits. It must be attached to a
+// client with WithRiskManager.
+func NewRiskManager(limits RiskLimits) *RiskManager {
+	return &RiskManager{
+		limits:  limits,
+		volume:  make(map[string]float64),
+		pending: make(map[string]int),
+		created: make(map[string]map[string]time.Time),
+	}
+}
+
+// Kill blocks every new order. When cancelOpen is true, active orders of
+// every market are cancelled as well.
+func (rm *RiskManager) Kill(ctx context.Context, cancelOpen bool) ([]*OrderResult, error) {
+	rm.mu.Lock()
+	rm.killed = true
+	rm.mu.Unlock()
+
+	if !cancelOpen {
+		return nil, nil
+	}
+	if rm.ps == nil {
+		return nil, errRiskDetached
+	}
+	return rm.ps.CancelAllOrders(ctx, nil)
+}
+
+// Resume allows new orders again after Kill.
+func (rm *RiskManager) Resume() {
+	rm.mu.Lock()
+	rm.killed = false
+	rm.mu.Unlock()
+}
+
+// Killed reports whether the kill switch is on.
+func (rm *RiskManager) Killed() bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.killed
+}
+
+// dailyVolume returns the volume traded today in market, resetting the
+// counters when the day changed. It must be called with rm.mu held.
+func (rm *RiskManager) dailyVolume(market string) float64 {
+	today := time.Now().UTC().Format("2006-01-02")
+	if rm.day != today {
+		rm.day = today
+		rm.volume = make(map[string]float64)
+	}
+	return rm.volume[market]
+}
+
+// Check returns a *RiskError when mor breaks any limit.
+func (rm *RiskManager) Check(mor *MarketOrderRequest) error {
+	_, err := rm.check(context.Background(), mor, false)
+	return err
+}
+
+// reserve checks mor like Check and, when it passes, takes its notional from
+// the daily volume and an open order slot in the same critical section, so
+// concurrent orders cannot all pass the limits. The reservation must be
+// given back with settle once the order was sent.
+func (rm *RiskManager) reserve(ctx context.Context, mor *MarketOrderRequest) (*riskReservation, error) {
+	return rm.check(ctx, mor, true)
+}
+
+func (rm *RiskManager) check(ctx context.Context, mor *MarketOrderRequest, reserve bool) (*riskReservation, error) {
+	market := strings.ToUpper(mor.Market)
+	notional := mor.Amount * float64(mor.Price)
+
+	if rm.Killed() {
+		return nil, &RiskError{RiskKillSwitch, market, "kill switch is on"}
+	}
+	if max, ok := rm.limits.MaxOrderNotional[market]; ok && notional > max {
+		return nil, &RiskError{RiskOrderNotional, market, fmt.Sprintf("notional %v exceeds %v", notional, max)}
+	}
+	if (rm.limits.PriceBand > 0 || rm.limits.MaxOpenOrders > 0) && rm.ps == nil {
+		return nil, errRiskDetached
+	}
+
+	if rm.limits.PriceBand > 0 {
+		pub := &PublicService{client: rm.ps.client}
+		tr, err := pub.GetTicker(market)
+		if err != nil {
+			return nil, err
+		}
+		if len(tr.Data) == 0 {
+			return nil, &RiskError{RiskPriceBand, market, "no ticker available"}
+		}
+		last, err := strconv.ParseFloat(tr.Data[0].LastPrice, 64)
+		if err != nil || last <= 0 {
+			return nil, &RiskError{RiskPriceBand, market, fmt.Sprintf("invalid last price %q", tr.Data[0].LastPrice)}
+		}
+		if dist := math.Abs(float64(mor.Price)-last) / last; dist > rm.limits.PriceBand {
+			return nil, &RiskError{RiskPriceBand, market, fmt.Sprintf("price %d is %.2f%% away from last price %v", mor.Price, dist*100, last)}
+		}
+	}
+
+	var listed map[string]bool
+	var listedAt time.Time
+	if rm.limits.MaxOpenOrders > 0 {
+		listed = make(map[string]bool)
+		listedAt = time.Now()
+		opts := &MarketOrderOptions{Market: market, Limit: 100}
+		for {
+			mor, err := rm.ps.getActiveOrders(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range mor.Data {
+				listed[o.ID] = true
+			}
+			if mor.Pagination == nil || int(mor.Pagination.Next) <= opts.Page {
+				break
+			}
+			opts.Page = int(mor.Pagination.Next)
+		}
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.killed {
+		return nil, &RiskError{RiskKillSwitch, market, "kill switch is on"}
+	}
+	volume := rm.dailyVolume(market)
+	if max, ok := rm.limits.MaxDailyVolume[market]; ok && volume+notional > max {
+		return nil, &RiskError{RiskDailyVolume, market, fmt.Sprintf("daily volume %v exceeds %v", volume+notional, max)}
+	}
+	if listed != nil {
+		open := len(listed) + rm.pending[market]
+		for id, sent := range rm.created[market] {
+			switch {
+			case listed[id]:
+			case sent.Before(listedAt):
+				// Sent before the listing and missing from it: it is
+				// no longer open.
+				delete(rm.created[market], id)
+			default:
+				open++
+			}
+		}
+		if open >= rm.limits.MaxOpenOrders {
+			return nil, &RiskError{RiskOpenOrders, market, fmt.Sprintf("%d orders already open", open)}
+		}
+	}
+
+	if !reserve {
+		return nil, nil
+	}
+	rm.volume[market] = volume + notional
+	rm.pending[market]++
+	return &riskReservation{market: market, notional: notional, day: rm.day}, nil
+}
+
+// settle gives back the reservation of an order once sent. o is the order
+// created, if known, and err the error of the request. Orders that
+// definitely failed return their volume; the others keep it and count as
+// open until a listing of active orders no longer shows them.
+func (rm *RiskManager) settle(res *riskReservation, o *MarketOrder, err error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.pending[res.market]--
+	if err != nil && !isAmbiguous(err) {
+		if rm.day == res.day {
+			rm.volume[res.market] -= res.notional
+		}
+		return
+	}
+
+	if rm.created[res.market] == nil {
+		rm.created[res.market] = make(map[string]time.Time)
+	}
+	id := ""
+	if o != nil {
+		id = o.ID
+	}
+	if id == "" {
+		rm.seq++
+		id = fmt.Sprintf("?%d", rm.seq)
+	}
+	rm.created[res.market][id] = time.Now()
+}
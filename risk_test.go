@@ -0,0 +1,173 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_RiskManager(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/ticker":
+			w.Write(getTickerResponse)
+		case "/v1/orders/active":
+			w.Write(getActiveOrdersResponse)
+		case "/v1/orders":
+			w.Write(getCreateOrderResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rm := NewRiskManager(RiskLimits{
+		MaxOrderNotional: map[string]float64{"ETHCLP": 5000},
+		MaxDailyVolume:   map[string]float64{"ETHCLP": 7000},
+		MaxOpenOrders:    3,
+		PriceBand:        0.1,
+	})
+	c := NewClient("some-key", "some-secret", WithRiskManager(rm))
+	c.PrivateService.client.client = httpCli
+
+	cases := []struct {
+		mor   *MarketOrderRequest
+		limit RiskLimit
+	}{
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy"}, 0},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 0.6, Price: 10000, Type: "buy"}, RiskOrderNotional},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 0.2, Price: 12000, Type: "buy"}, RiskPriceBand},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 0.45, Price: 10000, Type: "buy"}, RiskDailyVolume},
+	}
+
+	for i, c2 := range cases {
+		_, err := c.CreateOrder(c2.mor)
+		if c2.limit == 0 {
+			if err != nil {
+				t.Errorf("Unexpected error in case %d: %v", i, err)
+			}
+			continue
+		}
+
+		var riskErr *RiskError
+		if !errors.As(err, &riskErr) || riskErr.Limit != c2.limit {
+			t.Errorf("Expected %s limit error in case %d, got %v", c2.limit, i, err)
+		}
+	}
+
+	if _, err := rm.Kill(context.Background(), false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.1, Price: 10000, Type: "buy"}
+	var riskErr *RiskError
+	if _, err := c.CreateOrder(mor); !errors.As(err, &riskErr) || riskErr.Limit != RiskKillSwitch {
+		t.Errorf("Expected kill switch error, got %v", err)
+	}
+
+	rm.Resume()
+	if _, err := c.CreateOrder(mor); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	rm.limits.MaxOpenOrders = 2
+	if err := rm.Check(mor); !errors.As(err, &riskErr) || riskErr.Limit != RiskOpenOrders {
+		t.Errorf("Expected open orders error, got %v", err)
+	}
+}
+
+func Test_RiskManager_Concurrent(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/orders/active":
+			w.Write(getEmptyActiveOrdersResponse)
+		case "/v1/orders":
+			time.Sleep(50 * time.Millisecond)
+			w.Write(getCreateOrderResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	cases := []struct {
+		limits RiskLimits
+		limit  RiskLimit
+	}{
+		{RiskLimits{MaxDailyVolume: map[string]float64{"ETHCLP": 4000}}, RiskDailyVolume},
+		{RiskLimits{MaxOpenOrders: 1}, RiskOpenOrders},
+	}
+	for _, c2 := range cases {
+		rm := NewRiskManager(c2.limits)
+		c := NewClient("some-key", "some-secret", WithRiskManager(rm))
+		c.PrivateService.client.client = httpCli
+
+		mors := make([]*MarketOrderRequest, 4)
+		for i := range mors {
+			mors[i] = &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy"}
+		}
+		results, _ := c.CreateOrders(context.Background(), mors)
+
+		created := 0
+		for _, r := range results {
+			var riskErr *RiskError
+			switch {
+			case r.Err == nil:
+				created++
+			case !errors.As(r.Err, &riskErr) || riskErr.Limit != c2.limit:
+				t.Errorf("Expected %s limit error, got %v", c2.limit, r.Err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("Expected 1 order created under %s limit, got %d", c2.limit, created)
+		}
+	}
+}
+
+func Test_RiskManager_Detached(t *testing.T) {
+	rm := NewRiskManager(RiskLimits{MaxOpenOrders: 1})
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy"}
+	if err := rm.Check(mor); err != errRiskDetached {
+		t.Errorf("Expected %v, got %v", errRiskDetached, err)
+	}
+	if _, err := rm.Kill(context.Background(), true); err != errRiskDetached {
+		t.Errorf("Expected %v, got %v", errRiskDetached, err)
+	}
+	if !rm.Killed() {
+		t.Errorf("Expected kill switch to be on")
+	}
+}
+
+var getEmptyActiveOrdersResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {
+		   "previous": "null",
+		   "limit": 100,
+		   "page": 0,
+		   "next": "null"
+		},
+		"data": []
+	}
+`)
+
+var getTickerResponse = []byte(`
+	{
+		"status": "success",
+		"data": [
+		   {
+			  "timestamp": "2017-09-01T14:01:56.887272",
+			  "market": "ETHCLP",
+			  "bid": "9900",
+			  "ask": "10100",
+			  "last_price": "10000",
+			  "low": "9500",
+			  "high": "10500",
+			  "volume": "120.5"
+		   }
+		]
+	}
+`)
@@ -0,0 +1,68 @@
+package socket
+
+import (
+	"sort"
+	"strconv"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+// BookUpdate represents one side of the book of a market after a snapshot
+// or a diff was applied.
+type BookUpdate struct {
+	Market string
+	// Type of the side, buy or sell.
+	Type string
+	// Data holds every price level of the side, best price first.
+	Data []*cryptomkt.Book
+}
+
+// ApplyTo replaces the side of ob updated by u.
+func (u *BookUpdate) ApplyTo(ob *cryptomkt.OrderBook) error {
+	return ob.SetSide(u.Type, u.Data)
+}
+
+// bookSide keeps the price levels of one side of a book keyed by price.
+type bookSide struct {
+	buy    bool
+	levels map[string]*cryptomkt.Book
+}
+
+func newBookSide(side string) *bookSide {
+	return &bookSide{buy: side == "buy", levels: make(map[string]*cryptomkt.Book)}
+}
+
+// reset replaces every level with levels.
+func (bs *bookSide) reset(levels []*cryptomkt.Book) {
+	bs.levels = make(map[string]*cryptomkt.Book, len(levels))
+	bs.apply(levels)
+}
+
+// apply upserts levels. Levels with a zero amount are removed.
+func (bs *bookSide) apply(levels []*cryptomkt.Book) {
+	for _, l := range levels {
+		if amount, err := strconv.ParseFloat(l.Amount, 64); err == nil && amount == 0 {
+			delete(bs.levels, l.Price)
+			continue
+		}
+		bs.levels[l.Price] = l
+	}
+}
+
+// sorted returns the levels sorted best price first, descending for buy
+// and ascending for sell.
+func (bs *bookSide) sorted() []*cryptomkt.Book {
+	levels := make([]*cryptomkt.Book, 0, len(bs.levels))
+	for _, l := range bs.levels {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		pi, _ := strconv.ParseFloat(levels[i].Price, 64)
+		pj, _ := strconv.ParseFloat(levels[j].Price, 64)
+		if bs.buy {
+			return pi > pj
+		}
+		return pi < pj
+	})
+	return levels
+}
@@ -0,0 +1,86 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Engine.IO packet types.
+const (
+	eioOpen    = '0'
+	eioClose   = '1'
+	eioPing    = '2'
+	eioPong    = '3'
+	eioMessage = '4'
+	eioNoop    = '6'
+)
+
+// Socket.IO packet types, carried inside Engine.IO messages.
+const (
+	sioConnect    = '0'
+	sioDisconnect = '1'
+	sioEvent      = '2'
+	sioError      = '4'
+)
+
+// handshake represents the payload of the Engine.IO open packet.
+type handshake struct {
+	SID          string `json:"sid"`
+	PingInterval int    `json:"pingInterval"`
+	PingTimeout  int    `json:"pingTimeout"`
+}
+
+func (h *handshake) pingEvery() time.Duration {
+	if h.PingInterval <= 0 {
+		return 25 * time.Second
+	}
+	return time.Duration(h.PingInterval) * time.Millisecond
+}
+
+// event represents a Socket.IO event.
+type event struct {
+	Name string
+	Data json.RawMessage
+}
+
+// encodeEvent returns the Engine.IO message emitting name with data.
+func encodeEvent(name string, data interface{}) (string, error) {
+	b, err := json.Marshal([]interface{}{name, data})
+	if err != nil {
+		return "", err
+	}
+	return string([]byte{eioMessage, sioEvent}) + string(b), nil
+}
+
+// decodeEvent parses the Socket.IO event of an Engine.IO message payload,
+// the part after the Engine.IO packet type.
+func decodeEvent(payload string) (*event, error) {
+	if len(payload) == 0 || payload[0] != sioEvent {
+		return nil, fmt.Errorf("socket: not an event packet %q", payload)
+	}
+
+	// Events may carry a namespace and an ack id before the JSON array.
+	body := payload[1:]
+	if i := strings.IndexByte(body, '['); i >= 0 {
+		body = body[i:]
+	}
+
+	var args []json.RawMessage
+	if err := json.Unmarshal([]byte(body), &args); err != nil {
+		return nil, fmt.Errorf("socket: invalid event %q, %v", payload, err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("socket: event without name %q", payload)
+	}
+
+	ev := &event{}
+	if err := json.Unmarshal(args[0], &ev.Name); err != nil {
+		return nil, fmt.Errorf("socket: invalid event name %q, %v", payload, err)
+	}
+	if len(args) > 1 {
+		ev.Data = args[1]
+	}
+	return ev, nil
+}
@@ -0,0 +1,656 @@
+// Package socket implements a client of the CryptoMarket socket service,
+// which pushes tickers, books, trades and balances in real time.
+//
+// The service speaks Socket.IO 2 (Engine.IO 3) over a websocket. Once
+// connected, the client authenticates with the credentials returned by
+// PrivateService.GetSocketAuth and subscribes to markets. Books arrive as an
+// open-book snapshot followed by open-book-diff messages, where a level with
+// a zero amount is removed. The client keeps the books, reconnects with an
+// exponential backoff and subscribes again to every market after
+// reconnecting.
+package socket
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+	"golang.org/x/net/websocket"
+)
+
+// DefaultURL is the address of the CryptoMarket socket service.
+const DefaultURL = "https://worker.cryptomkt.com"
+
+// Events sent and received by the client.
+const (
+	eventUserAuth       = "user-auth"
+	eventSubscribe      = "subscribe"
+	eventUnsubscribe    = "unsubscribe"
+	eventTicker         = "ticker"
+	eventOpenBook       = "open-book"
+	eventOpenBookDiff   = "open-book-diff"
+	eventHistoricalBook = "historical-book"
+	eventBalance        = "balance"
+)
+
+const (
+	// channelSize is the buffer of every channel exposed by Client.
+	channelSize = 100
+	// maxSeenTrades bounds the trade IDs remembered to drop duplicates.
+	maxSeenTrades = 10000
+)
+
+// ErrClosed is returned when using a closed client.
+var ErrClosed = errors.New("socket: client closed")
+
+// Authenticator returns the credentials of a socket connection.
+// *cryptomkt.PrivateService implements it.
+type Authenticator interface {
+	GetSocketAuth() (*cryptomkt.SocketAuthResponse, error)
+}
+
+// Config represents the configuration of a Client.
+type Config struct {
+	// URL of the socket service. DefaultURL when empty.
+	URL string
+	// TLSConfig used to dial secure connections.
+	TLSConfig *tls.Config
+	// ReconnectDelay is the first delay before reconnecting. Default 1 second.
+	ReconnectDelay time.Duration
+	// MaxReconnectDelay caps the backoff between reconnects. Default 30 seconds.
+	MaxReconnectDelay time.Duration
+	// OnError is called with connection errors, the client keeps
+	// reconnecting, and with the updates dropped because their channel is
+	// not read.
+	OnError func(err error)
+}
+
+// Client represents a connection to the socket service.
+type Client struct {
+	auth Authenticator
+	cfg  Config
+
+	sendMu sync.Mutex
+	conn   *websocket.Conn
+
+	mu      sync.Mutex
+	markets map[string]bool
+	books   map[string]map[string]*bookSide
+	seen    map[string]bool
+	order   []string
+	closed  bool
+	done    chan struct{}
+
+	tickers  chan *cryptomkt.Ticker
+	updates  chan *BookUpdate
+	trades   chan *cryptomkt.Trade
+	balances chan []*cryptomkt.Balance
+}
+
+// Dial connects and authenticates to the socket service. cfg may be nil.
+func Dial(ctx context.Context, auth Authenticator, cfg *Config) (*Client, error) {
+	c := &Client{
+		auth:     auth,
+		markets:  make(map[string]bool),
+		books:    make(map[string]map[string]*bookSide),
+		seen:     make(map[string]bool),
+		done:     make(chan struct{}),
+		tickers:  make(chan *cryptomkt.Ticker, channelSize),
+		updates:  make(chan *BookUpdate, channelSize),
+		trades:   make(chan *cryptomkt.Trade, channelSize),
+		balances: make(chan []*cryptomkt.Balance, channelSize),
+	}
+	if cfg != nil {
+		c.cfg = *cfg
+	}
+	if c.cfg.URL == "" {
+		c.cfg.URL = DefaultURL
+	}
+	if c.cfg.ReconnectDelay <= 0 {
+		c.cfg.ReconnectDelay = time.Second
+	}
+	if c.cfg.MaxReconnectDelay <= 0 {
+		c.cfg.MaxReconnectDelay = 30 * time.Second
+	}
+
+	conn, hs, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go c.run(conn, hs)
+
+	return c, nil
+}
+
+// Tickers returns the channel receiving ticker updates.
+func (c *Client) Tickers() <-chan *cryptomkt.Ticker {
+	return c.tickers
+}
+
+// Books returns the channel receiving book sides after every snapshot or
+// diff.
+func (c *Client) Books() <-chan *BookUpdate {
+	return c.updates
+}
+
+// Trades returns the channel receiving new trades, without duplicates.
+func (c *Client) Trades() <-chan *cryptomkt.Trade {
+	return c.trades
+}
+
+// Balances returns the channel receiving the balance of every wallet.
+func (c *Client) Balances() <-chan []*cryptomkt.Balance {
+	return c.balances
+}
+
+// Subscribe subscribes to the channels of markets. Subscriptions are renewed
+// after every reconnect.
+func (c *Client) Subscribe(markets ...string) error {
+	markets = upper(markets)
+
+	c.mu.Lock()
+	for _, m := range markets {
+		c.markets[m] = true
+	}
+	c.mu.Unlock()
+
+	return c.emit(eventSubscribe, markets)
+}
+
+// Unsubscribe stops receiving the channels of markets.
+func (c *Client) Unsubscribe(markets ...string) error {
+	markets = upper(markets)
+
+	c.mu.Lock()
+	for _, m := range markets {
+		delete(c.markets, m)
+		delete(c.books, m)
+	}
+	c.mu.Unlock()
+
+	return c.emit(eventUnsubscribe, markets)
+}
+
+// upper returns a copy of markets in upper case.
+func upper(markets []string) []string {
+	up := make([]string, len(markets))
+	for i, m := range markets {
+		up[i] = strings.ToUpper(m)
+	}
+	return up
+}
+
+// Close closes the connection and every channel of the client.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	c.mu.Unlock()
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// websocketURL returns the Engine.IO websocket endpoint of the service URL.
+func websocketURL(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/socket.io/"
+	u.RawQuery = "EIO=3&transport=websocket"
+
+	return u.String(), origin, nil
+}
+
+// connect dials the service, waits for the Socket.IO connect packet,
+// authenticates and subscribes again to the known markets.
+func (c *Client) connect(ctx context.Context) (*websocket.Conn, *handshake, error) {
+	wsURL, origin, err := websocketURL(c.cfg.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	wsCfg, err := websocket.NewConfig(wsURL, origin)
+	if err != nil {
+		return nil, nil, err
+	}
+	wsCfg.TlsConfig = c.cfg.TLSConfig
+
+	conn, err := wsCfg.DialContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("socket: dial %s failed, %v", wsURL, err)
+	}
+
+	hs, err := waitConnect(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	sar, err := c.auth.GetSocketAuth()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if sar.Data == nil {
+		conn.Close()
+		return nil, nil, errors.New("socket: empty auth response")
+	}
+
+	// Close may run during a reconnect: it closes c.conn, so the new
+	// conn is only set while the client is open.
+	c.sendMu.Lock()
+	if c.isClosed() {
+		c.sendMu.Unlock()
+		conn.Close()
+		return nil, nil, ErrClosed
+	}
+	c.conn = conn
+	c.sendMu.Unlock()
+
+	if err := c.emit(eventUserAuth, sar.Data); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	markets := make([]string, 0, len(c.markets))
+	for m := range c.markets {
+		markets = append(markets, m)
+	}
+	c.books = make(map[string]map[string]*bookSide)
+	c.mu.Unlock()
+
+	if len(markets) > 0 {
+		sort.Strings(markets)
+		if err := c.emit(eventSubscribe, markets); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+
+	return conn, hs, nil
+}
+
+// waitConnect reads the Engine.IO open packet and the Socket.IO connect
+// packet.
+func waitConnect(conn *websocket.Conn) (*handshake, error) {
+	var hs *handshake
+	for {
+		var msg string
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			return nil, fmt.Errorf("socket: handshake failed, %v", err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+
+		switch msg[0] {
+		case eioOpen:
+			hs = &handshake{}
+			if err := json.Unmarshal([]byte(msg[1:]), hs); err != nil {
+				return nil, fmt.Errorf("socket: invalid handshake %q, %v", msg, err)
+			}
+		case eioMessage:
+			if len(msg) > 1 && msg[1] == sioConnect {
+				if hs == nil {
+					hs = &handshake{}
+				}
+				return hs, nil
+			}
+			if len(msg) > 1 && msg[1] == sioError {
+				return nil, fmt.Errorf("socket: connection refused, %s", msg[2:])
+			}
+		}
+	}
+}
+
+// emit sends an event through the current connection.
+func (c *Client) emit(name string, data interface{}) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+
+	msg, err := encodeEvent(name, data)
+	if err != nil {
+		return err
+	}
+	return c.send(msg)
+}
+
+func (c *Client) send(msg string) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return websocket.Message.Send(c.conn, msg)
+}
+
+// run reads conn until it fails, then reconnects until the client is closed.
+func (c *Client) run(conn *websocket.Conn, hs *handshake) {
+	defer func() {
+		close(c.tickers)
+		close(c.updates)
+		close(c.trades)
+		close(c.balances)
+	}()
+
+	for {
+		err := c.read(conn, hs)
+		conn.Close()
+		if c.isClosed() {
+			return
+		}
+		c.report(err)
+
+		delay := c.cfg.ReconnectDelay
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-time.After(delay):
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-c.done:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			conn, hs, err = c.connect(ctx)
+			cancel()
+			if err == nil {
+				break
+			}
+			if c.isClosed() {
+				return
+			}
+			c.report(err)
+
+			delay *= 2
+			if delay > c.cfg.MaxReconnectDelay {
+				delay = c.cfg.MaxReconnectDelay
+			}
+		}
+	}
+}
+
+func (c *Client) report(err error) {
+	if err != nil && c.cfg.OnError != nil {
+		c.cfg.OnError(err)
+	}
+}
+
+// read dispatches the messages of conn and pings the service until conn
+// fails.
+func (c *Client) read(conn *websocket.Conn, hs *handshake) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(hs.pingEvery())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := c.send(string(eioPing)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	timeout := hs.pingEvery() + time.Duration(hs.PingTimeout)*time.Millisecond
+	if hs.PingTimeout <= 0 {
+		timeout = 2 * hs.pingEvery()
+	}
+	for {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		var msg string
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			return fmt.Errorf("socket: connection lost, %v", err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+
+		switch msg[0] {
+		case eioPing:
+			if err := c.send(string(eioPong) + msg[1:]); err != nil {
+				return err
+			}
+		case eioClose:
+			return errors.New("socket: connection closed by server")
+		case eioMessage:
+			if len(msg) < 2 {
+				continue
+			}
+			switch msg[1] {
+			case sioEvent:
+				ev, err := decodeEvent(msg[1:])
+				if err != nil {
+					c.report(err)
+					continue
+				}
+				if err := c.dispatch(ev); err != nil {
+					c.report(err)
+				}
+			case sioDisconnect:
+				return errors.New("socket: disconnected by server")
+			case sioError:
+				return fmt.Errorf("socket: server error, %s", msg[2:])
+			}
+		}
+	}
+}
+
+// dispatch decodes an event and forwards it to its channel.
+func (c *Client) dispatch(ev *event) error {
+	switch ev.Name {
+	case eventTicker:
+		var tickers map[string]*cryptomkt.Ticker
+		if err := json.Unmarshal(ev.Data, &tickers); err != nil {
+			return fmt.Errorf("socket: invalid %s event, %v", ev.Name, err)
+		}
+		for market, t := range tickers {
+			if t.Market == "" {
+				t.Market = market
+			}
+			c.sendTicker(t)
+		}
+	case eventOpenBook:
+		var books map[string]struct {
+			Buy  []*cryptomkt.Book `json:"buy"`
+			Sell []*cryptomkt.Book `json:"sell"`
+		}
+		if err := json.Unmarshal(ev.Data, &books); err != nil {
+			return fmt.Errorf("socket: invalid %s event, %v", ev.Name, err)
+		}
+		for market, b := range books {
+			c.updateBook(market, "buy", b.Buy, true)
+			c.updateBook(market, "sell", b.Sell, true)
+		}
+	case eventOpenBookDiff:
+		var diff struct {
+			Market string            `json:"market"`
+			Type   string            `json:"type"`
+			Data   []*cryptomkt.Book `json:"data"`
+		}
+		if err := json.Unmarshal(ev.Data, &diff); err != nil {
+			return fmt.Errorf("socket: invalid %s event, %v", ev.Name, err)
+		}
+		c.updateBook(diff.Market, diff.Type, diff.Data, false)
+	case eventHistoricalBook:
+		var trades map[string][]*cryptomkt.Trade
+		if err := json.Unmarshal(ev.Data, &trades); err != nil {
+			return fmt.Errorf("socket: invalid %s event, %v", ev.Name, err)
+		}
+		for market, ts := range trades {
+			for _, t := range ts {
+				if t.Market == "" {
+					t.Market = market
+				}
+				if !c.markSeen(t.Tid) {
+					continue
+				}
+				c.sendTrade(t)
+			}
+		}
+	case eventBalance:
+		var wallets map[string]*cryptomkt.Balance
+		if err := json.Unmarshal(ev.Data, &wallets); err != nil {
+			return fmt.Errorf("socket: invalid %s event, %v", ev.Name, err)
+		}
+		balances := make([]*cryptomkt.Balance, 0, len(wallets))
+		for wallet, b := range wallets {
+			if b.Wallet == "" {
+				b.Wallet = wallet
+			}
+			balances = append(balances, b)
+		}
+		sort.Slice(balances, func(i, j int) bool {
+			return balances[i].Wallet < balances[j].Wallet
+		})
+		c.sendBalances(balances)
+	}
+
+	return nil
+}
+
+// sendTicker sends t without blocking, so a consumer that stops reading a
+// channel does not stall the connection. When the channel is full the
+// oldest ticker is dropped, only the latest ones matter. Drops are reported
+// to OnError.
+func (c *Client) sendTicker(t *cryptomkt.Ticker) {
+	for {
+		select {
+		case c.tickers <- t:
+			return
+		default:
+		}
+		select {
+		case <-c.tickers:
+			c.report(errDropped("ticker"))
+		default:
+		}
+	}
+}
+
+// sendUpdate sends update like sendTicker.
+func (c *Client) sendUpdate(update *BookUpdate) {
+	for {
+		select {
+		case c.updates <- update:
+			return
+		default:
+		}
+		select {
+		case <-c.updates:
+			c.report(errDropped("book"))
+		default:
+		}
+	}
+}
+
+// sendBalances sends balances like sendTicker.
+func (c *Client) sendBalances(balances []*cryptomkt.Balance) {
+	for {
+		select {
+		case c.balances <- balances:
+			return
+		default:
+		}
+		select {
+		case <-c.balances:
+			c.report(errDropped("balance"))
+		default:
+		}
+	}
+}
+
+// sendTrade sends t without blocking. Trades that do not fit are dropped
+// and reported to OnError.
+func (c *Client) sendTrade(t *cryptomkt.Trade) {
+	select {
+	case c.trades <- t:
+	default:
+		c.report(errDropped("trade"))
+	}
+}
+
+// errDropped returns the error reported when an update of kind is dropped
+// because its channel is not read.
+func errDropped(kind string) error {
+	return fmt.Errorf("socket: %s channel is not read, update dropped", kind)
+}
+
+// markSeen records a trade ID and reports whether it was new.
+func (c *Client) markSeen(tid string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.seen[tid] {
+		return false
+	}
+	c.seen[tid] = true
+	c.order = append(c.order, tid)
+	for len(c.order) > maxSeenTrades {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+	return true
+}
+
+// updateBook applies a snapshot or a diff to one side of a book and sends
+// the resulting side.
+func (c *Client) updateBook(market, side string, levels []*cryptomkt.Book, snapshot bool) {
+	market = strings.ToUpper(market)
+	if side != "buy" && side != "sell" {
+		return
+	}
+
+	c.mu.Lock()
+	sides, ok := c.books[market]
+	if !ok {
+		sides = map[string]*bookSide{"buy": newBookSide("buy"), "sell": newBookSide("sell")}
+		c.books[market] = sides
+	}
+	if snapshot {
+		sides[side].reset(levels)
+	} else {
+		sides[side].apply(levels)
+	}
+	update := &BookUpdate{Market: market, Type: side, Data: sides[side].sorted()}
+	c.mu.Unlock()
+
+	c.sendUpdate(update)
+}
@@ -0,0 +1,230 @@
+package socket
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+	"golang.org/x/net/websocket"
+)
+
+type fakeAuth struct{}
+
+func (fakeAuth) GetSocketAuth() (*cryptomkt.SocketAuthResponse, error) {
+	return &cryptomkt.SocketAuthResponse{
+		Status: "success",
+		Data:   &cryptomkt.SocketAuth{UID: "10", SocID: "some-socid"},
+	}, nil
+}
+
+// fakeServer accepts socket connections, drops the first one right after it
+// subscribes and pushes fixed events to the next ones.
+func fakeServer(t *testing.T, subscribed chan<- []string) *httptest.Server {
+	var conns int32
+	return httptest.NewTLSServer(websocket.Handler(func(ws *websocket.Conn) {
+		n := atomic.AddInt32(&conns, 1)
+		websocket.Message.Send(ws, `0{"sid":"abc","pingInterval":25000,"pingTimeout":60000}`)
+		websocket.Message.Send(ws, "40")
+
+		authenticated := false
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			if msg[0] != eioMessage {
+				continue
+			}
+			ev, err := decodeEvent(msg[1:])
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			switch ev.Name {
+			case eventUserAuth:
+				var auth cryptomkt.SocketAuth
+				json.Unmarshal(ev.Data, &auth)
+				authenticated = auth.SocID == "some-socid"
+			case eventSubscribe:
+				if !authenticated {
+					t.Errorf("Expected user-auth before subscribe")
+				}
+				var markets []string
+				json.Unmarshal(ev.Data, &markets)
+				subscribed <- markets
+				if n == 1 {
+					ws.Close()
+					return
+				}
+				for _, e := range fakeEvents {
+					websocket.Message.Send(ws, e)
+				}
+			}
+		}
+	}))
+}
+
+var fakeEvents = []string{
+	`42["open-book",{"ETHCLP":{"buy":[{"price":"9900","amount":"1"},{"price":"9950","amount":"2"}],"sell":[{"price":"10100","amount":"1"}]}}]`,
+	`42["open-book-diff",{"market":"ETHCLP","type":"buy","data":[{"price":"9950","amount":"0"},{"price":"9980","amount":"0.5"}]}]`,
+	`42["ticker",{"ETHCLP":{"last_price":"10000","bid":"9980","ask":"10100"}}]`,
+	`42["historical-book",{"ETHCLP":[{"tid":"1","price":"10000","amount":"0.1"},{"tid":"1","price":"10000","amount":"0.1"},{"tid":"2","price":"10010","amount":"0.2"}]}]`,
+	`42["balance",{"ETH":{"available":"1.5","balance":"2"}}]`,
+}
+
+func Test_Client(t *testing.T) {
+	subscribed := make(chan []string, 2)
+	srv := fakeServer(t, subscribed)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, fakeAuth{}, &Config{
+		URL:            srv.URL,
+		TLSConfig:      &tls.Config{InsecureSkipVerify: true},
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	if err := c.Subscribe("ethclp"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case markets := <-subscribed:
+			if len(markets) != 1 || markets[0] != "ETHCLP" {
+				t.Errorf("Expected subscription to ETHCLP, got %v", markets)
+			}
+		case <-ctx.Done():
+			t.Errorf("Expected subscription %d", i+1)
+			return
+		}
+	}
+
+	var last *BookUpdate
+	for i := 0; i < 3; i++ {
+		select {
+		case last = <-c.Books():
+		case <-ctx.Done():
+			t.Errorf("Expected book update %d", i+1)
+			return
+		}
+	}
+	if last.Type != "buy" || len(last.Data) != 2 || last.Data[0].Price != "9980" || last.Data[1].Price != "9900" {
+		t.Errorf("Expected buy levels 9980 and 9900, got %+v", last)
+	}
+
+	tk := <-c.Tickers()
+	if tk.Market != "ETHCLP" || tk.LastPrice != "10000" {
+		t.Errorf("Expected ETHCLP ticker at 10000, got %+v", tk)
+	}
+
+	for _, expected := range []string{"1", "2"} {
+		if tr := <-c.Trades(); tr.Tid != expected {
+			t.Errorf("Expected trade %s, got %s", expected, tr.Tid)
+		}
+	}
+
+	balances := <-c.Balances()
+	if len(balances) != 1 || balances[0].Wallet != "ETH" || balances[0].Available != 1.5 {
+		t.Errorf("Expected ETH balance, got %+v", balances)
+	}
+
+	c.Close()
+	select {
+	case _, ok := <-c.Trades():
+		if ok {
+			t.Errorf("Unexpected duplicated trade")
+		}
+	case <-ctx.Done():
+		t.Errorf("Expected channels to be closed")
+	}
+}
+
+func Test_ClientSlowConsumer(t *testing.T) {
+	srv := httptest.NewTLSServer(websocket.Handler(func(ws *websocket.Conn) {
+		websocket.Message.Send(ws, `0{"sid":"abc","pingInterval":25000,"pingTimeout":60000}`)
+		websocket.Message.Send(ws, "40")
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			if ev, err := decodeEvent(msg[1:]); err != nil || ev.Name != eventSubscribe {
+				continue
+			}
+			for i := 0; i < 2*channelSize; i++ {
+				websocket.Message.Send(ws, fmt.Sprintf(`42["open-book-diff",{"market":"ETHCLP","type":"buy","data":[{"price":"%d","amount":"1"}]}]`, 9000+i))
+			}
+			websocket.Message.Send(ws, fakeEvents[2])
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var dropped int32
+	c, err := Dial(ctx, fakeAuth{}, &Config{
+		URL:       srv.URL,
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		OnError:   func(error) { atomic.AddInt32(&dropped, 1) },
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	defer c.Close()
+	if err := c.Subscribe("ETHCLP"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	// Books are never read: the ticker must still arrive.
+	select {
+	case tk := <-c.Tickers():
+		if tk.LastPrice != "10000" {
+			t.Errorf("Expected ticker at 10000, got %+v", tk)
+		}
+	case <-ctx.Done():
+		t.Errorf("Expected ticker despite the unread books")
+		return
+	}
+	if n := atomic.LoadInt32(&dropped); n != channelSize {
+		t.Errorf("Expected %d book updates dropped, got %d", channelSize, n)
+	}
+	// The latest book is kept.
+	var last *BookUpdate
+	for len(c.Books()) > 0 {
+		last = <-c.Books()
+	}
+	if last == nil || len(last.Data) != 2*channelSize {
+		t.Errorf("Expected the latest book with %d levels, got %+v", 2*channelSize, last)
+	}
+}
+
+func Test_decodeEvent(t *testing.T) {
+	ev, err := decodeEvent(`2/market,7["ticker",{"ETHCLP":{}}]`)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if ev.Name != "ticker" || string(ev.Data) != `{"ETHCLP":{}}` {
+		t.Errorf("Unexpected event %s %s", ev.Name, ev.Data)
+	}
+
+	if _, err := decodeEvent(`0`); err == nil {
+		t.Errorf("Expected error for connect packet")
+	}
+}
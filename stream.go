@@ -0,0 +1,408 @@
+package cryptomkt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSeenTrades bounds the number of trade IDs remembered per market to
+// detect duplicates.
+const maxSeenTrades = 10000
+
+// maxTradePages bounds the pages of trades fetched by a poll to reach the
+// last trade seen.
+const maxTradePages = 10
+
+// StreamOptions represents the polling configuration of a Stream.
+type StreamOptions struct {
+	// TickerInterval is the polling interval of tickers. Default 5 seconds.
+	TickerInterval time.Duration
+	// TradesInterval is the polling interval of trades. Default 5 seconds.
+	TradesInterval time.Duration
+	// BookInterval is the polling interval of books. Default 5 seconds.
+	BookInterval time.Duration
+	// OnError is called with every polling error, with the gaps of trades
+	// too many to fetch between two polls and with the trades dropped for
+	// subscribers that do not keep up. Polling continues.
+	OnError func(err error)
+}
+
+// Stream turns the public endpoints into streams of changes by polling them.
+// Subscribers of the same data share a single poller, which stops when every
+// subscription context is done.
+type Stream struct {
+	ps   *PublicService
+	opts StreamOptions
+
+	mu      sync.Mutex
+	pollers map[string]*poller
+}
+
+// subscription represents a subscriber of a poller.
+type subscription struct {
+	ctx context.Context
+	// send delivers v without blocking and reports false when it was
+	// dropped because the subscriber is not reading.
+	send  func(v interface{}) bool
+	close func()
+	fresh bool
+}
+
+// delivery is a subscriber of a poll. New subscribers receive the current
+// state instead of the changes.
+type delivery struct {
+	sub   *subscription
+	fresh bool
+}
+
+// poller polls one endpoint and forwards its changes to subscribers.
+type poller struct {
+	key      string
+	interval time.Duration
+	// ctx is cancelled once every subscription context is done, aborting
+	// the fetch in progress.
+	ctx    context.Context
+	cancel func()
+	// fetch returns the changes since the previous call.
+	fetch func(ctx context.Context) ([]interface{}, error)
+	// state is the last known state for new subscribers, nil for streams of
+	// events such as trades.
+	state func() []interface{}
+	subs  []*subscription
+	// wake is signalled when a subscription context is done.
+	wake chan struct{}
+}
+
+// NewStream returns a Stream polling ps. opts may be nil.
+func NewStream(ps *PublicService, opts *StreamOptions) *Stream {
+	s := &Stream{ps: ps, pollers: make(map[string]*poller)}
+	if opts != nil {
+		s.opts = *opts
+	}
+	for _, d := range []*time.Duration{&s.opts.TickerInterval, &s.opts.TradesInterval, &s.opts.BookInterval} {
+		if *d <= 0 {
+			*d = 5 * time.Second
+		}
+	}
+	return s
+}
+
+// subscribe adds sub to the poller of key, starting it with newPoller when it
+// is not running.
+func (s *Stream) subscribe(key string, newPoller func() *poller, sub *subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A poller being stopped is replaced by a new one.
+	p, ok := s.pollers[key]
+	if !ok || p.ctx.Err() != nil {
+		p = newPoller()
+		p.key = key
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+		p.wake = make(chan struct{}, 1)
+		s.pollers[key] = p
+		go s.run(p)
+	}
+	sub.fresh = true
+	p.subs = append(p.subs, sub)
+
+	go func() {
+		<-sub.ctx.Done()
+		s.mu.Lock()
+		done := true
+		for _, sub := range p.subs {
+			done = done && sub.ctx.Err() != nil
+		}
+		if done {
+			p.cancel()
+		}
+		s.mu.Unlock()
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// prune closes the subscriptions of p whose context is done and returns the
+// remaining ones. When none remain, p is removed from the stream. New
+// subscriptions stay marked as fresh until they are delivered.
+func (s *Stream) prune(p *poller, deliver bool) []delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := p.subs[:0]
+	for _, sub := range p.subs {
+		if sub.ctx.Err() != nil {
+			sub.close()
+			continue
+		}
+		active = append(active, sub)
+	}
+	p.subs = active
+	if len(active) == 0 {
+		p.cancel()
+		if s.pollers[p.key] == p {
+			delete(s.pollers, p.key)
+		}
+		return nil
+	}
+
+	deliveries := make([]delivery, len(active))
+	for i, sub := range active {
+		deliveries[i] = delivery{sub: sub, fresh: sub.fresh}
+		if deliver {
+			sub.fresh = false
+		}
+	}
+	return deliveries
+}
+
+// run polls until p has no subscribers left. Sends never block, so a
+// subscriber that stops reading does not delay the others.
+func (s *Stream) run(p *poller) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		changes, err := p.fetch(p.ctx)
+		if err != nil && s.opts.OnError != nil {
+			s.opts.OnError(err)
+		}
+
+		deliveries := s.prune(p, true)
+		if deliveries == nil {
+			return
+		}
+
+		var state []interface{}
+		if p.state != nil {
+			state = p.state()
+		}
+		for _, d := range deliveries {
+			values := changes
+			if d.fresh && p.state != nil {
+				values = state
+			}
+			for i, v := range values {
+				if !d.sub.send(v) {
+					if s.opts.OnError != nil {
+						s.opts.OnError(fmt.Errorf("cryptopay: %s subscriber is not reading, %d updates dropped", p.key, len(values)-i))
+					}
+					break
+				}
+			}
+		}
+
+	wait:
+		for {
+			select {
+			case <-ticker.C:
+				break wait
+			case <-p.wake:
+				if s.prune(p, false) == nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SubscribeTicker returns a channel receiving the ticker of market every time
+// it changes, starting with its current value. A ticker not read before the
+// next change is replaced by it. The channel is closed once ctx is done.
+func (s *Stream) SubscribeTicker(ctx context.Context, market string) <-chan *Ticker {
+	ch := make(chan *Ticker, 1)
+	s.subscribe("ticker:"+strings.ToUpper(market), func() *poller {
+		var last *Ticker
+		return &poller{
+			interval: s.opts.TickerInterval,
+			fetch: func(ctx context.Context) ([]interface{}, error) {
+				tr, err := s.ps.getTicker(ctx, fmt.Sprintf("/ticker?market=%s", market))
+				if err != nil || len(tr.Data) == 0 {
+					return nil, err
+				}
+				t := tr.Data[0]
+				if last != nil && sameTicker(last, t) {
+					return nil, nil
+				}
+				last = t
+				return []interface{}{t}, nil
+			},
+			state: func() []interface{} {
+				if last == nil {
+					return nil
+				}
+				return []interface{}{last}
+			},
+		}
+	}, &subscription{
+		ctx: ctx,
+		send: func(v interface{}) bool {
+			// Only the latest ticker matters: replace the unread one.
+			for {
+				select {
+				case ch <- v.(*Ticker):
+					return true
+				default:
+				}
+				select {
+				case <-ch:
+				default:
+				}
+			}
+		},
+		close: func() { close(ch) },
+	})
+	return ch
+}
+
+// sameTicker compares two tickers ignoring their timestamp.
+func sameTicker(a, b *Ticker) bool {
+	x, y := *a, *b
+	x.Timestamp, y.Timestamp = "", ""
+	return x == y
+}
+
+// SubscribeTrades returns a channel receiving every new trade of market, in
+// chronological order. Trades already done when the poller starts are not
+// sent. Pages of trades are fetched back to the last trade seen, up to 10
+// pages; older trades are skipped and the gap is reported to OnError. The
+// channel buffers 100 trades; trades that do not fit are dropped and
+// reported to OnError. The channel is closed once ctx is done.
+func (s *Stream) SubscribeTrades(ctx context.Context, market string) <-chan *Trade {
+	ch := make(chan *Trade, 100)
+	s.subscribe("trades:"+strings.ToUpper(market), func() *poller {
+		seen := make(map[string]bool)
+		order := make([]string, 0)
+		started := false
+		return &poller{
+			interval: s.opts.TradesInterval,
+			fetch: func(ctx context.Context) ([]interface{}, error) {
+				var (
+					trades = make([]*Trade, 0)
+					got    = make(map[string]bool)
+					gapErr error
+				)
+				opts := &TradesOptions{Market: market, Limit: 100}
+				for {
+					tr, err := s.ps.getTrades(ctx, opts)
+					if err != nil {
+						return nil, err
+					}
+
+					caughtUp := !started
+					for _, t := range tr.Data {
+						if seen[t.Tid] {
+							caughtUp = true
+							continue
+						}
+						// New trades shift the pages, so a trade may be
+						// fetched twice.
+						if !got[t.Tid] {
+							got[t.Tid] = true
+							trades = append(trades, t)
+						}
+					}
+					if caughtUp || tr.Pagination == nil || int(tr.Pagination.Next) <= opts.Page {
+						break
+					}
+					if opts.Page+1 >= maxTradePages {
+						gapErr = fmt.Errorf("cryptopay: %s more than %d pages of trades since the last poll, older trades skipped", market, maxTradePages)
+						break
+					}
+					opts.Page = int(tr.Pagination.Next)
+				}
+
+				for _, t := range trades {
+					seen[t.Tid] = true
+					order = append(order, t.Tid)
+				}
+				for len(order) > maxSeenTrades {
+					delete(seen, order[0])
+					order = order[1:]
+				}
+
+				if !started {
+					started = true
+					return nil, nil
+				}
+
+				sort.SliceStable(trades, func(i, j int) bool {
+					return trades[i].Timestamp < trades[j].Timestamp
+				})
+				changes := make([]interface{}, len(trades))
+				for i, t := range trades {
+					changes[i] = t
+				}
+				return changes, gapErr
+			},
+		}
+	}, &subscription{
+		ctx: ctx,
+		send: func(v interface{}) bool {
+			select {
+			case ch <- v.(*Trade):
+				return true
+			default:
+				return false
+			}
+		},
+		close: func() { close(ch) },
+	})
+	return ch
+}
+
+// SubscribeBook returns a channel receiving the first page of one side of
+// the book of market, buy or sell, every time it changes, starting with its
+// current value. A book not read before the next change is replaced by it.
+// The channel is closed once ctx is done.
+func (s *Stream) SubscribeBook(ctx context.Context, market, side string) <-chan []*Book {
+	ch := make(chan []*Book, 1)
+	s.subscribe("book:"+strings.ToUpper(market)+":"+side, func() *poller {
+		var last []*Book
+		return &poller{
+			interval: s.opts.BookInterval,
+			fetch: func(ctx context.Context) ([]interface{}, error) {
+				br, err := s.ps.getOrdersBook(ctx, &BooksOptions{Market: market, Type: side})
+				if err != nil {
+					return nil, err
+				}
+				if last != nil && reflect.DeepEqual(last, br.Data) {
+					return nil, nil
+				}
+				last = br.Data
+				return []interface{}{br.Data}, nil
+			},
+			state: func() []interface{} {
+				if last == nil {
+					return nil
+				}
+				return []interface{}{last}
+			},
+		}
+	}, &subscription{
+		ctx: ctx,
+		send: func(v interface{}) bool {
+			// Only the latest book matters: replace the unread one.
+			for {
+				select {
+				case ch <- v.([]*Book):
+					return true
+				default:
+				}
+				select {
+				case <-ch:
+				default:
+				}
+			}
+		},
+		close: func() { close(ch) },
+	})
+	return ch
+}
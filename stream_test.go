@@ -0,0 +1,289 @@
+package cryptomkt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_StreamTicker(t *testing.T) {
+	var calls int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		price := 10000
+		if atomic.AddInt32(&calls, 1) > 2 {
+			price = 10100
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": [{"market": "ETHCLP", "last_price": "%d"}]}`, price)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	s := NewStream(ps, &StreamOptions{TickerInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := s.SubscribeTicker(ctx, "ETHCLP")
+	second := s.SubscribeTicker(ctx, "ethclp")
+
+	s.mu.Lock()
+	pollers := len(s.pollers)
+	s.mu.Unlock()
+	if pollers != 1 {
+		t.Errorf("Expected subscribers to share 1 poller, got %d", pollers)
+	}
+
+	// A subscriber reading late may only see the latest ticker.
+	for _, ch := range []<-chan *Ticker{first, second} {
+		select {
+		case tk := <-ch:
+			if tk.LastPrice == "10000" {
+				select {
+				case tk = <-ch:
+				case <-time.After(time.Second):
+				}
+			}
+			if tk.LastPrice != "10100" {
+				t.Errorf("Expected last price 10100, got %s", tk.LastPrice)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Expected ticker")
+			return
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-first:
+		for ok {
+			_, ok = <-first
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected channel to be closed")
+	}
+}
+
+func Test_StreamTrades(t *testing.T) {
+	var calls int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Write(getStreamTradesResponse)
+			return
+		}
+		w.Write(getStreamNewTradesResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	s := NewStream(ps, &StreamOptions{TradesInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trades := s.SubscribeTrades(ctx, "ETHCLP")
+
+	for _, expected := range []string{"1003", "1004"} {
+		select {
+		case tr := <-trades:
+			if tr.Tid != expected {
+				t.Errorf("Expected trade %s, got %s", expected, tr.Tid)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Expected trade %s", expected)
+			return
+		}
+	}
+
+	select {
+	case tr := <-trades:
+		t.Errorf("Unexpected duplicated trade %s", tr.Tid)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func Test_StreamTradesPages(t *testing.T) {
+	var calls int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		switch n {
+		case 1, 3:
+			w.Write(getStreamTradesResponse)
+		case 2:
+			fmt.Fprint(w, `{"status": "success", "pagination": {"page": 0, "next": 1}, "data": [
+				{"tid": "1004", "timestamp": "2017-09-01T14:02:01.000000", "market": "ETHCLP"},
+				{"tid": "1003", "timestamp": "2017-09-01T14:02:00.000000", "market": "ETHCLP"}
+			]}`)
+		default:
+			// Every page holds new trades, the last seen is never reached.
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			fmt.Fprintf(w, `{"status": "success", "pagination": {"page": %d, "next": %d}, "data": [
+				{"tid": "%d-%d", "timestamp": "2017-09-01T14:03:00.000000", "market": "ETHCLP"}
+			]}`, page, page+1, n, page)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	errs := make(chan error, 10)
+	s := NewStream(ps, &StreamOptions{
+		TradesInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trades := s.SubscribeTrades(ctx, "ETHCLP")
+
+	// The new trades span two pages.
+	for _, expected := range []string{"1003", "1004"} {
+		select {
+		case tr := <-trades:
+			if tr.Tid != expected {
+				t.Errorf("Expected trade %s, got %s", expected, tr.Tid)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Expected trade %s", expected)
+			return
+		}
+	}
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "more than 10 pages of trades") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the gap of trades to be reported")
+	}
+}
+
+func Test_StreamContext(t *testing.T) {
+	block := make(chan struct{})
+	var calls int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Write(getStreamTradesResponse)
+			return
+		}
+		<-block
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	defer close(block)
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	s := NewStream(ps, &StreamOptions{TradesInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	trades := s.SubscribeTrades(ctx, "ETHCLP")
+	for atomic.LoadInt32(&calls) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The fetch in progress is aborted once the subscription is done.
+	cancel()
+	select {
+	case _, ok := <-trades:
+		if ok {
+			t.Errorf("Expected no trades")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected channel to be closed while the fetch is blocked")
+	}
+}
+
+func Test_StreamSlowSubscriber(t *testing.T) {
+	var calls int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/v1/ticker" {
+			fmt.Fprintf(w, `{"status": "success", "data": [{"market": "ETHCLP", "last_price": "%d"}]}`, 10000+n)
+			return
+		}
+		trades := make([]string, 60)
+		for i := range trades {
+			trades[i] = fmt.Sprintf(`{"tid": "%d-%d", "timestamp": "2017-09-01T14:02:%02d.000000", "market": "ETHCLP"}`, n, i, i)
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": [%s]}`, strings.Join(trades, ","))
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	dropped := make(chan error, 100)
+	s := NewStream(ps, &StreamOptions{
+		TickerInterval: 10 * time.Millisecond,
+		TradesInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case dropped <- err:
+			default:
+			}
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.SubscribeTicker(ctx, "ETHCLP")
+	tickers := s.SubscribeTicker(ctx, "ETHCLP")
+	var last string
+	for i := 0; i < 5; i++ {
+		select {
+		case tk := <-tickers:
+			if tk.LastPrice == last {
+				t.Errorf("Expected a new ticker, got %s again", last)
+			}
+			last = tk.LastPrice
+		case <-time.After(time.Second):
+			t.Errorf("Expected ticker %d while another subscriber is not reading", i)
+			return
+		}
+	}
+
+	s.SubscribeTrades(ctx, "ETHCLP")
+	trades := s.SubscribeTrades(ctx, "ETHCLP")
+	for i := 0; i < 150; i++ {
+		select {
+		case <-trades:
+		case <-time.After(time.Second):
+			t.Errorf("Expected trade %d while another subscriber is not reading", i)
+			return
+		}
+	}
+	select {
+	case err := <-dropped:
+		if !strings.Contains(err.Error(), "trades:ETHCLP subscriber is not reading") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected dropped trades to be reported")
+	}
+}
+
+var getStreamTradesResponse = []byte(`
+	{
+		"status": "success",
+		"data": [
+		   {"market_taker": "buy", "price": "10000", "amount": "0.1", "tid": "1002", "timestamp": "2017-09-01T14:01:57.000000", "market": "ETHCLP"},
+		   {"market_taker": "sell", "price": "9990", "amount": "0.2", "tid": "1001", "timestamp": "2017-09-01T14:01:56.000000", "market": "ETHCLP"}
+		]
+	}
+`)
+
+var getStreamNewTradesResponse = []byte(`
+	{
+		"status": "success",
+		"data": [
+		   {"market_taker": "buy", "price": "10010", "amount": "0.4", "tid": "1004", "timestamp": "2017-09-01T14:02:01.000000", "market": "ETHCLP"},
+		   {"market_taker": "buy", "price": "10005", "amount": "0.3", "tid": "1003", "timestamp": "2017-09-01T14:02:00.000000", "market": "ETHCLP"},
+		   {"market_taker": "buy", "price": "10000", "amount": "0.1", "tid": "1002", "timestamp": "2017-09-01T14:01:57.000000", "market": "ETHCLP"}
+		]
+	}
+`)
@@ -0,0 +1,157 @@
+package cryptomkt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TickerSnapshot represents a ticker with its values parsed.
+type TickerSnapshot struct {
+	Market string
+	Bid    float64
+	Ask    float64
+	// Last is the price of the last trade.
+	Last float64
+	// High, Low and Volume cover the last 24 hours.
+	High   float64
+	Low    float64
+	Volume float64
+	Time   time.Time
+}
+
+// NewTickerSnapshot parses t.
+func NewTickerSnapshot(t *Ticker) (*TickerSnapshot, error) {
+	ts := &TickerSnapshot{Market: t.Market}
+	fields := []struct {
+		name  string
+		value string
+		dst   *float64
+	}{
+		{"bid", t.Bid, &ts.Bid},
+		{"ask", t.Ask, &ts.Ask},
+		{"last_price", t.LastPrice, &ts.Last},
+		{"high", t.High, &ts.High},
+		{"low", t.Low, &ts.Low},
+		{"volume", t.Volume, &ts.Volume},
+	}
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cryptopay: invalid ticker %s %q", f.name, f.value)
+		}
+		*f.dst = v
+	}
+
+	if t.Timestamp != "" {
+		tm, err := time.Parse(dateLayout, t.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("cryptopay: invalid ticker timestamp %q", t.Timestamp)
+		}
+		ts.Time = tm
+	}
+
+	return ts, nil
+}
+
+// Mid returns the price halfway between the bid and the ask.
+func (ts *TickerSnapshot) Mid() float64 {
+	return (ts.Bid + ts.Ask) / 2
+}
+
+// Spread returns the difference between the ask and the bid.
+func (ts *TickerSnapshot) Spread() float64 {
+	return ts.Ask - ts.Bid
+}
+
+// Range24h returns the difference between the 24 hours high and low.
+func (ts *TickerSnapshot) Range24h() float64 {
+	return ts.High - ts.Low
+}
+
+// Change returns the relative change of the last price since prev, for
+// example 0.01 for 1%. The ticker has no opening price, so the 24 hours
+// change is Change of a snapshot taken a day before.
+func (ts *TickerSnapshot) Change(prev *TickerSnapshot) float64 {
+	if prev == nil || prev.Last == 0 {
+		return 0
+	}
+	return (ts.Last - prev.Last) / prev.Last
+}
+
+// TickerResult represents the ticker of a single market of GetTickers.
+type TickerResult struct {
+	Ticker   *Ticker
+	Snapshot *TickerSnapshot
+	// Err is the error returned for this market, if any.
+	Err error
+}
+
+// GetTickers returns the tickers of markets keyed by market. Without markets
+// every ticker is fetched in one request, otherwise one request per market
+// is sent concurrently under the rate limit of the client. Failed markets
+// carry their error and are also aggregated in the returned *BatchError.
+func (ps *PublicService) GetTickers(ctx context.Context, markets ...string) (map[string]*TickerResult, error) {
+	var results map[string]*TickerResult
+	if len(markets) == 0 {
+		tr, err := ps.getTicker(ctx, "/ticker")
+		if err != nil {
+			return nil, err
+		}
+		results = make(map[string]*TickerResult, len(tr.Data))
+		for _, t := range tr.Data {
+			market := strings.ToUpper(t.Market)
+			results[market] = newTickerResult(t)
+			markets = append(markets, market)
+		}
+	} else {
+		var mu sync.Mutex
+		results = make(map[string]*TickerResult, len(markets))
+		runWorkers(ctx, len(markets), func(i int, err error) {
+			market := strings.ToUpper(markets[i])
+			r := &TickerResult{Err: err}
+			if err == nil {
+				r = ps.fetchTicker(ctx, market)
+			}
+			mu.Lock()
+			results[market] = r
+			mu.Unlock()
+		})
+	}
+
+	var errs []error
+	for _, market := range markets {
+		if r := results[strings.ToUpper(market)]; r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	if len(errs) > 0 {
+		return results, &BatchError{Errors: errs}
+	}
+	return results, nil
+}
+
+// fetchTicker gets the ticker of a single market.
+func (ps *PublicService) fetchTicker(ctx context.Context, market string) *TickerResult {
+	tr, err := ps.getTicker(ctx, fmt.Sprintf("/ticker?market=%s", market))
+	if err != nil {
+		return &TickerResult{Err: fmt.Errorf("cryptopay: ticker %s: %w", market, err)}
+	}
+	for _, t := range tr.Data {
+		if strings.EqualFold(t.Market, market) {
+			return newTickerResult(t)
+		}
+	}
+	return &TickerResult{Err: fmt.Errorf("cryptopay: no ticker for market %s", market)}
+}
+
+func newTickerResult(t *Ticker) *TickerResult {
+	ts, err := NewTickerSnapshot(t)
+	if err != nil {
+		err = fmt.Errorf("cryptopay: ticker %s: %w", strings.ToUpper(t.Market), err)
+	}
+	return &TickerResult{Ticker: t, Snapshot: ts, Err: err}
+}
@@ -0,0 +1,122 @@
+package cryptomkt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func Test_GetTickers(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("market") {
+		case "":
+			w.Write(getTickersResponse)
+		case "ETHCLP":
+			w.Write(getTickerResponse)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"status": "error", "message": "invalid_market"}`)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	all, err := ps.GetTickers(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if len(all) != 2 || all["BTCCLP"] == nil || all["BTCCLP"].Snapshot.Last != 4500000 {
+		t.Errorf("Expected tickers of ETHCLP and BTCCLP, got %+v", all)
+	}
+
+	tickers, err := ps.GetTickers(context.Background(), "ethclp", "XXXCLP")
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 {
+		t.Errorf("Expected 1 failed market, got %v", err)
+		return
+	}
+	if tickers["XXXCLP"].Err == nil {
+		t.Errorf("Expected error for XXXCLP")
+	}
+
+	eth := tickers["ETHCLP"]
+	if eth == nil || eth.Err != nil {
+		t.Errorf("Unexpected ETHCLP result: %+v", eth)
+		return
+	}
+	if mid := eth.Snapshot.Mid(); mid != 10000 {
+		t.Errorf("Expected mid 10000, got %v", mid)
+	}
+	if eth.Snapshot.Time.IsZero() {
+		t.Errorf("Expected ticker timestamp to be parsed")
+	}
+}
+
+func Test_GetTickers_InvalidTicker(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status": "success", "data": [
+			{"market": "ETHCLP", "bid": "9900", "ask": "10100", "last_price": "10000", "low": "9500", "high": "10500", "volume": "120.5"},
+			{"market": "DOGECLP", "bid": "1", "ask": "2", "last_price": "n/a", "low": "1", "high": "2", "volume": "5"}
+		]}`)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PublicService{client: &httpClient{client: httpCli}}
+
+	tickers, err := ps.GetTickers(context.Background())
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 {
+		t.Errorf("Expected 1 invalid ticker, got %v", err)
+		return
+	}
+	if tickers["DOGECLP"].Err == nil || tickers["ETHCLP"].Err != nil {
+		t.Errorf("Expected only DOGECLP to fail, got %+v", tickers)
+	}
+}
+
+func Test_TickerSnapshotChange(t *testing.T) {
+	prev := &TickerSnapshot{Last: 10000}
+	cur := &TickerSnapshot{Last: 10500, High: 10600, Low: 9800}
+
+	if c := cur.Change(prev); c != 0.05 {
+		t.Errorf("Expected change 0.05, got %v", c)
+	}
+	if r := cur.Range24h(); r != 800 {
+		t.Errorf("Expected range 800, got %v", r)
+	}
+	if c := cur.Change(nil); c != 0 {
+		t.Errorf("Expected no change without previous snapshot, got %v", c)
+	}
+}
+
+var getTickersResponse = []byte(`
+	{
+		"status": "success",
+		"data": [
+		   {
+			  "timestamp": "2017-09-01T14:01:56.887272",
+			  "market": "ETHCLP",
+			  "bid": "9900",
+			  "ask": "10100",
+			  "last_price": "10000",
+			  "low": "9500",
+			  "high": "10500",
+			  "volume": "120.5"
+		   },
+		   {
+			  "timestamp": "2017-09-01T14:01:56.887272",
+			  "market": "BTCCLP",
+			  "bid": "4490000",
+			  "ask": "4510000",
+			  "last_price": "4500000",
+			  "low": "4400000",
+			  "high": "4600000",
+			  "volume": "3.2"
+		   }
+		]
+	}
+`)
@@ -0,0 +1,91 @@
+// Package tracing creates OpenTelemetry spans for the calls of a cryptomkt
+// client.
+//
+// Tracer is a cryptomkt.Instrumentation starting one client span per
+// request, and provides an interceptor starting one span per call of a
+// decorated API, with retries as events:
+//
+//	tracer := tracing.New(nil)
+//	client := cryptomkt.NewClient(key, secret, cryptomkt.WithInstrumentation(tracer))
+//	trading := cryptomkt.InterceptTrading(&client.PrivateService,
+//		tracer.Interceptor(), cryptomkt.RetryInterceptor(3, time.Second))
+//
+// The API methods take no context, so request spans are not children of
+// call spans.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+)
+
+const instrumentationName = "github.com/Finciero/go-cryptomkt/tracing"
+
+// Tracer starts the spans of a client.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ cryptomkt.Instrumentation = (*Tracer)(nil)
+
+// New returns a tracer using tp, or the global tracer provider when tp is
+// nil.
+func New(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// StartRequest implements cryptomkt.Instrumentation interface.
+func (t *Tracer) StartRequest(ctx context.Context, info *cryptomkt.RequestInfo) context.Context {
+	ctx, _ = t.tracer.Start(ctx, info.Method+" "+info.Endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", info.Method),
+			attribute.String("cryptomkt.endpoint", info.Endpoint),
+		),
+	)
+	return ctx
+}
+
+// EndRequest implements cryptomkt.Instrumentation interface.
+func (t *Tracer) EndRequest(ctx context.Context, info *cryptomkt.RequestInfo) {
+	span := trace.SpanFromContext(ctx)
+	if info.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.status_code", info.StatusCode))
+	}
+	end(span, info.Err)
+}
+
+// Interceptor returns an interceptor starting one span per call, named
+// after the method. Retries made by a cryptomkt.RetryInterceptor placed
+// after it are recorded as events of the span.
+func (t *Tracer) Interceptor() cryptomkt.Interceptor {
+	return func(ctx context.Context, method string, call func(ctx context.Context) error) error {
+		ctx, span := t.tracer.Start(ctx, "cryptomkt."+method)
+		ctx = cryptomkt.WithRetryHook(ctx, func(attempt int, err error) {
+			span.AddEvent("retry", trace.WithAttributes(
+				attribute.Int("attempt", attempt),
+				attribute.String("error", err.Error()),
+			))
+		})
+		err := call(ctx)
+		end(span, err)
+		return err
+	}
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	cryptomkt "github.com/Finciero/go-cryptomkt"
+	"github.com/Finciero/go-cryptomkt/cryptomkttest"
+)
+
+func Test_Request(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tr := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+	info := &cryptomkt.RequestInfo{Endpoint: "/balance", Method: "GET"}
+	ctx := tr.StartRequest(context.Background(), info)
+	info.StatusCode = 401
+	info.Err = errors.New("invalid_key")
+	tr.EndRequest(ctx, info)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Errorf("Expected 1 span, got %d", len(spans))
+		return
+	}
+	s := spans[0]
+	if s.Name() != "GET /balance" || s.Status().Code != codes.Error {
+		t.Errorf("Unexpected span %s with status %v", s.Name(), s.Status())
+	}
+}
+
+func Test_Interceptor(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tr := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+	calls := 0
+	fake := &cryptomkttest.TradingAPI{
+		GetBalanceFunc: func() (*cryptomkt.BalanceResponse, error) {
+			calls++
+			if calls < 3 {
+				return nil, &cryptomkt.TransportError{Err: errors.New("connection reset")}
+			}
+			return &cryptomkt.BalanceResponse{Status: "success"}, nil
+		},
+	}
+	api := cryptomkt.InterceptTrading(fake, tr.Interceptor(), cryptomkt.RetryInterceptor(3, 0))
+	if _, err := api.GetBalance(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Errorf("Expected 1 span, got %d", len(spans))
+		return
+	}
+	s := spans[0]
+	if s.Name() != "cryptomkt.GetBalance" || s.Status().Code == codes.Error {
+		t.Errorf("Unexpected span %s with status %v", s.Name(), s.Status())
+	}
+	if len(s.Events()) != 2 || s.Events()[0].Name != "retry" {
+		t.Errorf("Expected 2 retry events, got %v", s.Events())
+	}
+}
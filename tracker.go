@@ -0,0 +1,288 @@
+package cryptomkt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// OrderEventType represents what happened to a tracked order.
+type OrderEventType int
+
+// Order event types emitted by OrderTracker.
+const (
+	OrderPartiallyFilled OrderEventType = iota + 1
+	OrderFilled
+	OrderCancelled
+)
+
+// String returns a human readable event type.
+func (et OrderEventType) String() string {
+	switch et {
+	case OrderPartiallyFilled:
+		return "partially-filled"
+	case OrderFilled:
+		return "filled"
+	case OrderCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// OrderEvent represents a change of a tracked order.
+type OrderEvent struct {
+	Type OrderEventType
+	// Order as returned by the API.
+	Order *MarketOrder
+	// Delta is the amount executed since the previous event of the order.
+	Delta float64
+}
+
+// trackedOrder is the state kept for every tracked order.
+type trackedOrder struct {
+	Market   string  `json:"market,omitempty"`
+	Executed float64 `json:"executed"`
+}
+
+// OrderTracker watches orders and emits an OrderEvent every time one of them
+// is partially filled, filled or cancelled. Filled and cancelled orders stop
+// being tracked.
+type OrderTracker struct {
+	ps *PrivateService
+
+	mu       sync.Mutex
+	orders   map[string]*trackedOrder
+	handlers []func(*OrderEvent)
+	errors   []func(error)
+}
+
+// NewOrderTracker returns a tracker that polls ps.
+func NewOrderTracker(ps *PrivateService) *OrderTracker {
+	return &OrderTracker{
+		ps:     ps,
+		orders: make(map[string]*trackedOrder),
+	}
+}
+
+// OnEvent registers fn to be called with every event, in registration order.
+func (ot *OrderTracker) OnEvent(fn func(*OrderEvent)) {
+	ot.mu.Lock()
+	ot.handlers = append(ot.handlers, fn)
+	ot.mu.Unlock()
+}
+
+// OnError registers fn to be called with the error of every failed request
+// while Run is running, in registration order.
+func (ot *OrderTracker) OnError(fn func(error)) {
+	ot.mu.Lock()
+	ot.errors = append(ot.errors, fn)
+	ot.mu.Unlock()
+}
+
+// Track starts tracking the order with the given ID. Its market is learned on
+// the next poll.
+func (ot *OrderTracker) Track(id string) {
+	ot.mu.Lock()
+	if _, ok := ot.orders[id]; !ok {
+		ot.orders[id] = &trackedOrder{}
+	}
+	ot.mu.Unlock()
+}
+
+// TrackOrder starts tracking o, typically the order returned by CreateOrder.
+func (ot *OrderTracker) TrackOrder(o *MarketOrder) {
+	to := &trackedOrder{Market: o.Market, Executed: executedAmount(o)}
+
+	ot.mu.Lock()
+	ot.orders[o.ID] = to
+	ot.mu.Unlock()
+}
+
+// Untrack stops tracking the order with the given ID.
+func (ot *OrderTracker) Untrack(id string) {
+	ot.mu.Lock()
+	delete(ot.orders, id)
+	ot.mu.Unlock()
+}
+
+// Tracked returns the IDs of the tracked orders.
+func (ot *OrderTracker) Tracked() []string {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	ids := make([]string, 0, len(ot.orders))
+	for id := range ot.orders {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// executedAmount returns the amount executed of o, derived from the remaining
+// amount for active orders.
+func executedAmount(o *MarketOrder) float64 {
+	if o.Amount == nil {
+		return 0
+	}
+	if o.Amount.Executed == 0 && o.Amount.Remaining > 0 {
+		return o.Amount.Original - o.Amount.Remaining
+	}
+	return o.Amount.Executed
+}
+
+// Poll checks every tracked order once. Orders with a known market are
+// checked with one GetActiveOrders listing per market, only orders missing
+// from those listings are requested one by one. A failed request does not
+// stop the others: their errors are returned in a *BatchError.
+func (ot *OrderTracker) Poll() error {
+	var errs []error
+	ot.poll(func(err error) { errs = append(errs, err) })
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BatchError{Errors: errs}
+}
+
+// poll checks every tracked order once and calls report with the error of
+// every failed request.
+func (ot *OrderTracker) poll(report func(error)) {
+	ot.mu.Lock()
+	byMarket := make(map[string]map[string]bool)
+	unknown := make([]string, 0)
+	for id, to := range ot.orders {
+		if to.Market == "" {
+			unknown = append(unknown, id)
+			continue
+		}
+		if byMarket[to.Market] == nil {
+			byMarket[to.Market] = make(map[string]bool)
+		}
+		byMarket[to.Market][id] = true
+	}
+	ot.mu.Unlock()
+
+	for market, ids := range byMarket {
+		opts := &MarketOrderOptions{Market: market, Limit: 100}
+		for {
+			mor, err := ot.ps.GetActiveOrders(opts)
+			if err != nil {
+				// Request the orders not listed yet one by one.
+				report(err)
+				break
+			}
+			for _, o := range mor.Data {
+				if ids[o.ID] {
+					delete(ids, o.ID)
+					ot.update(o)
+				}
+			}
+			if mor.Pagination == nil || int(mor.Pagination.Next) <= opts.Page {
+				break
+			}
+			opts.Page = int(mor.Pagination.Next)
+		}
+
+		for id := range ids {
+			unknown = append(unknown, id)
+		}
+	}
+
+	for _, id := range unknown {
+		morr, err := ot.ps.GetOrderStatus(&OrderStatusOption{ID: id})
+		if err != nil {
+			report(fmt.Errorf("cryptopay: order %s status failed, %w", id, err))
+			continue
+		}
+		if morr.Data != nil {
+			ot.update(morr.Data)
+		}
+	}
+}
+
+// update compares o with its tracked state and emits the resulting event.
+func (ot *OrderTracker) update(o *MarketOrder) {
+	ot.mu.Lock()
+	to, ok := ot.orders[o.ID]
+	if !ok {
+		ot.mu.Unlock()
+		return
+	}
+
+	to.Market = o.Market
+	executed := executedAmount(o)
+	delta := executed - to.Executed
+	to.Executed = executed
+
+	var ev *OrderEvent
+	switch o.Status {
+	case "executed":
+		ev = &OrderEvent{Type: OrderFilled, Order: o, Delta: delta}
+		delete(ot.orders, o.ID)
+	case "cancelled":
+		ev = &OrderEvent{Type: OrderCancelled, Order: o, Delta: delta}
+		delete(ot.orders, o.ID)
+	default:
+		if delta > 0 {
+			ev = &OrderEvent{Type: OrderPartiallyFilled, Order: o, Delta: delta}
+		}
+	}
+	handlers := ot.handlers
+	ot.mu.Unlock()
+
+	if ev == nil {
+		return
+	}
+	for _, fn := range handlers {
+		fn(ev)
+	}
+}
+
+// Run polls every interval until ctx is done. The error of every failed
+// request is passed to the functions registered with OnError and polling
+// goes on.
+func (ot *OrderTracker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ot.poll(func(err error) {
+			ot.mu.Lock()
+			handlers := ot.errors
+			ot.mu.Unlock()
+			for _, fn := range handlers {
+				fn(err)
+			}
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Save writes the tracked orders to w as JSON.
+func (ot *OrderTracker) Save(w io.Writer) error {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	return json.NewEncoder(w).Encode(ot.orders)
+}
+
+// Restore reads orders written by Save and adds them to the tracked set.
+func (ot *OrderTracker) Restore(r io.Reader) error {
+	orders := make(map[string]*trackedOrder)
+	if err := json.NewDecoder(r).Decode(&orders); err != nil {
+		return err
+	}
+
+	ot.mu.Lock()
+	for id, to := range orders {
+		ot.orders[id] = to
+	}
+	ot.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package cryptomkt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_OrderTracker(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/orders/active":
+			w.Write(getPartiallyFilledOrdersResponse)
+		case "/v1/orders/status":
+			w.Write(getCancelOrderResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	ot := NewOrderTracker(ps)
+	saved := `{"M103966":{"market":"ETHCLP","executed":0},"M103967":{"market":"ETHCLP","executed":0}}`
+	if err := ot.Restore(strings.NewReader(saved)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	events := make(map[string]*OrderEvent)
+	ot.OnEvent(func(ev *OrderEvent) {
+		events[ev.Order.ID] = ev
+	})
+
+	if err := ot.Poll(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	partial := events["M103966"]
+	if partial == nil || partial.Type != OrderPartiallyFilled || math.Abs(partial.Delta-0.4044) > 1e-9 {
+		t.Errorf("Expected M103966 to be partially filled by 0.4044, got %+v", partial)
+	}
+
+	cancelled := events["M103967"]
+	if cancelled == nil || cancelled.Type != OrderCancelled {
+		t.Errorf("Expected M103967 to be cancelled, got %+v", cancelled)
+	}
+
+	var buf bytes.Buffer
+	if err := ot.Save(&buf); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	if strings.Contains(buf.String(), "M103967") || !strings.Contains(buf.String(), "M103966") {
+		t.Errorf("Expected only M103966 to be tracked, got %s", buf.String())
+	}
+}
+
+func Test_OrderTracker_RunKeepsPolling(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		n := polls
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write(getInvalidOrderResponse)
+			return
+		}
+		w.Write(getCancelOrderResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	ot := NewOrderTracker(ps)
+	ot.Track("M103967")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	ot.OnError(func(err error) {
+		errs <- err
+	})
+	ot.OnEvent(func(ev *OrderEvent) {
+		if ev.Type == OrderCancelled {
+			cancel()
+		}
+	})
+
+	if err := ot.Run(ctx, 10*time.Millisecond); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	var apiErr *APIError
+	if err := <-errs; !errors.As(err, &apiErr) {
+		t.Errorf("Expected *APIError, got %v", err)
+	}
+}
+
+func Test_OrderTracker_PollKeepsGoing(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") == "stale" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(getInvalidOrderResponse)
+			return
+		}
+		w.Write(getCancelOrderResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	ot := NewOrderTracker(ps)
+	ot.Track("stale")
+	ot.Track("M103967")
+	cancelled := false
+	ot.OnEvent(func(ev *OrderEvent) {
+		cancelled = cancelled || ev.Order.ID == "M103967" && ev.Type == OrderCancelled
+	})
+
+	err := ot.Poll()
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("Expected one error for the stale order, got %v", err)
+	}
+	if !cancelled {
+		t.Errorf("Expected M103967 to be polled despite the stale order")
+	}
+}
+
+var getPartiallyFilledOrdersResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {
+		   "previous": "null",
+		   "limit": 100,
+		   "page": 0,
+		   "next": "null"
+		},
+		"data": [
+		   {
+			  "status": "active",
+			  "created_at": "2017-09-01T14:01:56.887272",
+			  "amount": {
+				 "original": "1.4044",
+				 "remaining": "1"
+			  },
+			  "price": "7120",
+			  "type": "buy",
+			  "id": "M103966",
+			  "market": "ETHCLP",
+			  "updated_at": "2017-09-01T14:05:56.887272"
+		   }
+		]
+	 }
+`)
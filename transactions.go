@@ -0,0 +1,206 @@
+package cryptomkt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+// TransactionType represents the kind of movement of a wallet.
+type TransactionType int
+
+// Transaction types returned by CryptoMarket.
+const (
+	TransactionTypeDeposit    TransactionType = 1
+	TransactionTypeWithdrawal TransactionType = 2
+)
+
+// String returns a human readable transaction type.
+func (tt TransactionType) String() string {
+	switch tt {
+	case TransactionTypeDeposit:
+		return "deposit"
+	case TransactionTypeWithdrawal:
+		return "withdrawal"
+	default:
+		return "unknown"
+	}
+}
+
+// TransactionStatus represents the state of a wallet movement.
+type TransactionStatus int
+
+// Transaction statuses returned by CryptoMarket.
+const (
+	TransactionStatusCancelled TransactionStatus = -1
+	TransactionStatusPending   TransactionStatus = 0
+	TransactionStatusConfirmed TransactionStatus = 1
+)
+
+// String returns a human readable transaction status.
+func (ts TransactionStatus) String() string {
+	switch ts {
+	case TransactionStatusCancelled:
+		return "cancelled"
+	case TransactionStatusPending:
+		return "pending"
+	case TransactionStatusConfirmed:
+		return "confirmed"
+	default:
+		return "unknown"
+	}
+}
+
+// Transaction represents a deposit or withdrawal of a wallet.
+type Transaction struct {
+	// ID de la transacción
+	ID string `json:"id,omitempty"`
+	// Tipo de transacción. 1 depósito, 2 retiro
+	Type TransactionType `json:"type,omitempty"`
+	// Estado de la transacción
+	Status TransactionStatus `json:"status,omitempty"`
+	// Monto de la transacción
+	Amount float64 `json:"amount,string,omitempty"`
+	// Porcentaje de comisión cobrado
+	FeePercent float64 `json:"fee_percent,string,omitempty"`
+	// Monto de la comisión cobrada
+	FeeAmount float64 `json:"fee_amount,string,omitempty"`
+	// Saldo de la billetera después de la transacción
+	Balance float64 `json:"balance,string,omitempty"`
+	// Fecha de la transacción
+	Date string `json:"date,omitempty"`
+	// Hash de la transacción en la red. Vacío en monedas fiat
+	Hash string `json:"hash,omitempty"`
+	// Dirección de origen o destino
+	Address string `json:"address,omitempty"`
+	// Memo de la transacción si la moneda lo requiere
+	Memo string `json:"memo,omitempty"`
+	// Moneda de la transacción
+	Currency string `json:"currency,omitempty"`
+}
+
+// TransactionsResponse represents a collection of transactions.
+type TransactionsResponse struct {
+	Status     string         `json:"status,omitempty"`
+	Data       []*Transaction `json:"data,omitempty"`
+	Pagination *Pagination    `json:"pagination,omitempty"`
+}
+
+// TransactionsOptions represents transactions query options.
+// Type, StartDate and EndDate are not supported by the API and are
+// applied to each page once it has been fetched.
+type TransactionsOptions struct {
+	// Moneda de la billetera
+	Currency string `url:"currency"`
+	// Página a consultar
+	Page int `url:"page,omitempty"`
+	// Límite de objetos por página. Por defecto es 20. Mínimo 20 , máximo 100
+	Limit int `url:"limit,omitempty"`
+	// Tipo de transacción a filtrar. 0 para todas
+	Type TransactionType `url:"-"`
+	// Fecha de inicio en formato 2006-01-02, inclusive
+	StartDate string `url:"-"`
+	// Fecha de término en formato 2006-01-02, inclusive
+	EndDate string `url:"-"`
+}
+
+func (opts *TransactionsOptions) match(t *Transaction) bool {
+	if opts.Type != 0 && t.Type != opts.Type {
+		return false
+	}
+
+	date := t.Date
+	if i := strings.IndexByte(date, 'T'); i >= 0 {
+		date = date[:i]
+	}
+	if opts.StartDate != "" && date < opts.StartDate {
+		return false
+	}
+	if opts.EndDate != "" && date > opts.EndDate {
+		return false
+	}
+
+	return true
+}
+
+// GetTransactions returns a page of deposits and withdrawals of a wallet.
+func (ps *PrivateService) GetTransactions(opts *TransactionsOptions) (*TransactionsResponse, error) {
+	ps.client.SetPrivate(ps.Private)
+	v, err := query.Values(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ps.client.get(fmt.Sprintf("/transactions?%s", v.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tr TransactionsResponse
+	if err := unmarshalJSON(resp.Body, &tr); err != nil {
+		return nil, err
+	}
+
+	data := tr.Data[:0]
+	for _, t := range tr.Data {
+		if opts.match(t) {
+			data = append(data, t)
+		}
+	}
+	tr.Data = data
+
+	return &tr, nil
+}
+
+// TransactionIterator walks every page of transactions of a wallet.
+type TransactionIterator struct {
+	ps   *PrivateService
+	opts TransactionsOptions
+	page []*Transaction
+	cur  *Transaction
+	done bool
+	err  error
+}
+
+// Transactions returns an iterator over all the transactions matching opts,
+// starting at opts.Page.
+func (ps *PrivateService) Transactions(opts *TransactionsOptions) *TransactionIterator {
+	return &TransactionIterator{ps: ps, opts: *opts}
+}
+
+// Next advances the iterator, fetching the next page when needed. It returns
+// false when there are no more transactions or an error happened.
+func (it *TransactionIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		tr, err := it.ps.GetTransactions(&it.opts)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page = tr.Data
+		if tr.Pagination == nil || int(tr.Pagination.Next) <= it.opts.Page {
+			it.done = true
+		} else {
+			it.opts.Page = int(tr.Pagination.Next)
+		}
+	}
+
+	it.cur, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionIterator) Transaction() *Transaction {
+	return it.cur
+}
+
+// Err returns the first error found while iterating.
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
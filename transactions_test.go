@@ -0,0 +1,166 @@
+package cryptomkt
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_GetTransactions(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(getTransactionsResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	opts := &TransactionsOptions{
+		Currency:  "ETH",
+		Type:      TransactionTypeDeposit,
+		StartDate: "2017-09-01",
+	}
+	tr, err := ps.GetTransactions(opts)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expectedLength := 1
+	actualLength := len(tr.Data)
+	if actualLength != expectedLength {
+		t.Errorf("Expected Data length to be %d, got %d", expectedLength, actualLength)
+		return
+	}
+
+	expectedFee := 0.0005
+	actualFee := tr.Data[0].FeeAmount
+	if actualFee != expectedFee {
+		t.Errorf("Expected fee amount to be %v, got %v", expectedFee, actualFee)
+		return
+	}
+
+	if tr.Data[0].Status != TransactionStatusConfirmed {
+		t.Errorf("Expected status %v, got %v", TransactionStatusConfirmed, tr.Data[0].Status)
+	}
+}
+
+func Test_TransactionIterator(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "1" {
+			w.Write(getTransactionsLastPageResponse)
+			return
+		}
+		w.Write(getTransactionsResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	it := ps.Transactions(&TransactionsOptions{Currency: "ETH"})
+	ids := make([]string, 0)
+	for it.Next() {
+		ids = append(ids, it.Transaction().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expectedLength := 4
+	if len(ids) != expectedLength {
+		t.Errorf("Expected %d transactions, got %d", expectedLength, len(ids))
+		return
+	}
+
+	expectedID := "T1004"
+	if ids[3] != expectedID {
+		t.Errorf("Expected last transaction to be %s, got %s", expectedID, ids[3])
+	}
+}
+
+var getTransactionsResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {
+		   "previous": "null",
+		   "limit": 20,
+		   "page": 0,
+		   "next": 1
+		},
+		"data": [
+		   {
+			  "id": "T1001",
+			  "type": 1,
+			  "status": 1,
+			  "amount": "1.5",
+			  "fee_percent": "0",
+			  "fee_amount": "0.0005",
+			  "balance": "11.3399",
+			  "date": "2017-09-01T14:01:56.887272",
+			  "hash": "0x9e3b...",
+			  "address": "0x2a5d...",
+			  "currency": "ETH"
+		   },
+		   {
+			  "id": "T1002",
+			  "type": 2,
+			  "status": 1,
+			  "amount": "0.5",
+			  "fee_percent": "0",
+			  "fee_amount": "0.005",
+			  "balance": "9.8399",
+			  "date": "2017-09-02T10:12:00.100000",
+			  "hash": "0x41ff...",
+			  "address": "0x7c1e...",
+			  "currency": "ETH"
+		   },
+		   {
+			  "id": "T1003",
+			  "type": 1,
+			  "status": 0,
+			  "amount": "2",
+			  "fee_percent": "0",
+			  "fee_amount": "0",
+			  "balance": "10.3399",
+			  "date": "2017-08-30T08:00:00.000000",
+			  "address": "0x2a5d...",
+			  "currency": "ETH"
+		   }
+		]
+	 }
+`)
+
+var getTransactionsLastPageResponse = []byte(`
+	{
+		"status": "success",
+		"pagination": {
+		   "previous": 0,
+		   "limit": 20,
+		   "page": 1,
+		   "next": "null"
+		},
+		"data": [
+		   {
+			  "id": "T1004",
+			  "type": 1,
+			  "status": -1,
+			  "amount": "0.1",
+			  "date": "2017-08-29T08:00:00.000000",
+			  "currency": "ETH"
+		   }
+		]
+	 }
+`)
@@ -0,0 +1,129 @@
+package cryptomkt
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrTransferNotAllowed is returned when the destination address of a
+// transfer is not part of the allow list configured on the client.
+var ErrTransferNotAllowed = errors.New("cryptopay: destination address not allowed")
+
+var (
+	btcLegacyAddress = regexp.MustCompile(`^[13][a-km-zA-HJ-NP-Z1-9]{25,34}$`)
+	btcBech32Address = regexp.MustCompile(`^bc1[ac-hj-np-z02-9]{39,59}$`)
+	ethAddress       = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	xlmAddress       = regexp.MustCompile(`^G[A-Z2-7]{55}$`)
+)
+
+// maxXLMMemoLength is the maximum length of a Stellar text memo.
+const maxXLMMemoLength = 28
+
+// ValidateAddress checks that address and memo are well formed for the given
+// currency. Only XLM accepts a memo.
+func ValidateAddress(currency, address, memo string) error {
+	switch strings.ToUpper(currency) {
+	case "BTC":
+		if !btcLegacyAddress.MatchString(address) && !btcBech32Address.MatchString(address) {
+			return fmt.Errorf("cryptopay: invalid BTC address %q", address)
+		}
+	case "ETH":
+		if !ethAddress.MatchString(address) {
+			return fmt.Errorf("cryptopay: invalid ETH address %q", address)
+		}
+	case "XLM":
+		if !xlmAddress.MatchString(address) {
+			return fmt.Errorf("cryptopay: invalid XLM address %q", address)
+		}
+		if len(memo) > maxXLMMemoLength {
+			return fmt.Errorf("cryptopay: XLM memo must be at most %d characters", maxXLMMemoLength)
+		}
+		return nil
+	default:
+		return fmt.Errorf("cryptopay: transfers of %s are not supported", currency)
+	}
+
+	if memo != "" {
+		return fmt.Errorf("cryptopay: %s transfers do not accept a memo", currency)
+	}
+	return nil
+}
+
+// normalizeAddress returns the canonical form of an address used to compare
+// it against the allow list. ETH addresses are case insensitive.
+func normalizeAddress(address string) string {
+	if ethAddress.MatchString(address) {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// TransferRequest represents a withdrawal to an external address.
+type TransferRequest struct {
+	// Dirección de destino
+	Address string `json:"address"`
+	// Monto a transferir
+	Amount float64 `json:"amount,string"`
+	// Moneda a transferir
+	Currency string `json:"currency"`
+	// Memo de destino. Solo para XLM
+	Memo string `json:"memo,omitempty"`
+	// DryRun validates the transfer without sending it.
+	DryRun bool `json:"-"`
+}
+
+// Params returns a map used to sign the requests.
+func (tr *TransferRequest) Params() url.Values {
+	form := url.Values{}
+
+	form.Add("address", tr.Address)
+	form.Add("amount", strconv.FormatFloat(tr.Amount, 'f', -1, 64))
+	form.Add("currency", strings.ToUpper(tr.Currency))
+	if tr.Memo != "" {
+		form.Add("memo", tr.Memo)
+	}
+
+	return form
+}
+
+// TransferResponse represents a transfer response.
+type TransferResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   string `json:"data,omitempty"`
+}
+
+// Transfer moves funds of a wallet to an external address. The address is
+// validated locally and checked against the allow list of the client before
+// the request is signed. Dry runs stop right after those checks.
+func (ps *PrivateService) Transfer(tr *TransferRequest) (*TransferResponse, error) {
+	if tr.Amount <= 0 {
+		return nil, errors.New("cryptopay: transfer amount must be positive")
+	}
+	if err := ValidateAddress(tr.Currency, tr.Address, tr.Memo); err != nil {
+		return nil, err
+	}
+	if ps.allowList != nil && !ps.allowList[normalizeAddress(tr.Address)] {
+		return nil, ErrTransferNotAllowed
+	}
+
+	if tr.DryRun {
+		return &TransferResponse{Status: "dry-run"}, nil
+	}
+
+	ps.client.SetPrivate(ps.Private)
+	resp, err := ps.client.postForm("/transfer", tr.Params())
+	if err != nil {
+		return nil, err
+	}
+
+	var r TransferResponse
+	if err := unmarshalJSON(resp.Body, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
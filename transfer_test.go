@@ -0,0 +1,118 @@
+package cryptomkt
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_ValidateAddress(t *testing.T) {
+	cases := []struct {
+		currency string
+		address  string
+		memo     string
+		valid    bool
+	}{
+		{"BTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "", true},
+		{"BTC", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", "", true},
+		{"BTC", "0OIl", "", false},
+		{"BTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "123", false},
+		{"ETH", "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "", true},
+		{"eth", "742d35Cc6634C0532925a3b844Bc454e4438f44e", "", false},
+		{"XLM", "GAHK7EEG2WWHVKDNT4CEQFZGKF2LGDSW2IVM4S5DP42RBW3K6BTODB4A", "123456", true},
+		{"XLM", "GAHK7EEG2WWHVKDNT4CEQFZGKF2LGDSW2IVM4S5DP42RBW3K6BTODB4A", "this memo is way too long to fit", false},
+		{"CLP", "some-bank-account", "", false},
+	}
+
+	for _, c := range cases {
+		err := ValidateAddress(c.currency, c.address, c.memo)
+		if c.valid && err != nil {
+			t.Errorf("Unexpected error for %s %s: %v", c.currency, c.address, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("Expected error for %s %s", c.currency, c.address)
+		}
+	}
+}
+
+func Test_Transfer(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if r.PostForm.Get("memo") != "123456" {
+			t.Errorf("Expected memo 123456, got %s", r.PostForm.Get("memo"))
+		}
+		w.Write(getTransferResponse)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	c := NewClient("some-key", "some-secret",
+		WithTransferAllowList("GAHK7EEG2WWHVKDNT4CEQFZGKF2LGDSW2IVM4S5DP42RBW3K6BTODB4A"))
+	c.PrivateService.client.client = httpCli
+
+	tr := &TransferRequest{
+		Address:  "GAHK7EEG2WWHVKDNT4CEQFZGKF2LGDSW2IVM4S5DP42RBW3K6BTODB4A",
+		Amount:   10.5,
+		Currency: "XLM",
+		Memo:     "123456",
+	}
+	resp, err := c.Transfer(tr)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expected := "success"
+	if resp.Status != expected {
+		t.Errorf("Expected status %s, got %s", expected, resp.Status)
+		return
+	}
+
+	tr.Address = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+	tr.Currency = "ETH"
+	tr.Memo = ""
+	if _, err := c.Transfer(tr); err != ErrTransferNotAllowed {
+		t.Errorf("Expected error %v, got %v", ErrTransferNotAllowed, err)
+	}
+}
+
+func Test_TransferDryRun(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to %s", r.URL.Path)
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	tr := &TransferRequest{
+		Address:  "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+		Amount:   0.25,
+		Currency: "ETH",
+		DryRun:   true,
+	}
+	resp, err := ps.Transfer(tr)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expected := "dry-run"
+	if resp.Status != expected {
+		t.Errorf("Expected status %s, got %s", expected, resp.Status)
+	}
+}
+
+var getTransferResponse = []byte(`
+	{
+		"status": "success",
+		"data": ""
+	}
+`)
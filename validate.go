@@ -0,0 +1,238 @@
+package cryptomkt
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quoteCurrencies are the currencies markets are quoted in, fiat first.
+var quoteCurrencies = []string{"CLP", "ARS", "BRL", "EUR", "MXN", "BTC", "ETH"}
+
+// fiatCurrencies are the quote currencies with integer prices.
+var fiatCurrencies = map[string]bool{"CLP": true, "ARS": true, "BRL": true, "EUR": true, "MXN": true}
+
+// minOrderAmounts are the minimum order amounts of each base currency.
+var minOrderAmounts = map[string]float64{
+	"BTC": 0.0001,
+	"ETH": 0.001,
+	"XLM": 1,
+	"EOS": 0.1,
+}
+
+// SplitMarket returns the base and quote currencies of a market, for example
+// ETH and CLP for ETHCLP.
+func SplitMarket(market string) (base, quote string, err error) {
+	market = strings.ToUpper(market)
+	for _, q := range quoteCurrencies {
+		if strings.HasSuffix(market, q) && len(market) > len(q) {
+			return market[:len(market)-len(q)], q, nil
+		}
+	}
+	return "", "", fmt.Errorf("cryptopay: unknown market %s", market)
+}
+
+// MarketRules represents the trading rules of a market.
+type MarketRules struct {
+	Market string
+	Base   string
+	Quote  string
+	// MinAmount is the minimum amount of base currency of an order.
+	MinAmount float64
+	// PriceTick is the price increment. Prices must be a multiple of it.
+	PriceTick int
+}
+
+// DefaultMarketRules returns the rules applied to market when none were set
+// with OrderValidator.SetRules.
+func DefaultMarketRules(market string) (*MarketRules, error) {
+	base, quote, err := SplitMarket(market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketRules{
+		Market:    base + quote,
+		Base:      base,
+		Quote:     quote,
+		MinAmount: minOrderAmounts[base],
+		PriceTick: 1,
+	}, nil
+}
+
+// ValidationError represents an order rejected before being sent.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+// Error implements error interface.
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("cryptopay: invalid order %s, %s", err.Field, err.Reason)
+}
+
+// OrderValidator checks orders against market rules and balances before they
+// are sent. Markets and balances are cached for ttl.
+type OrderValidator struct {
+	ps  *PrivateService
+	ttl time.Duration
+
+	mu       sync.Mutex
+	rules    map[string]*MarketRules
+	markets  map[string]bool
+	balances map[string]*Balance
+	locked   map[string]float64
+	// reserved holds the funds locked by each validation of an order since
+	// the last refresh, so Release never unlocks funds of an older balance.
+	reserved  map[*MarketOrderRequest][]reservation
+	fetchedAt time.Time
+}
+
+// reservation represents the funds of a wallet locked by an order.
+type reservation struct {
+	wallet string
+	amount float64
+}
+
+// NewOrderValidator returns a validator that fetches balances and markets
+// through ps.
+func NewOrderValidator(ps *PrivateService, ttl time.Duration) *OrderValidator {
+	return &OrderValidator{
+		ps:    ps,
+		ttl:   ttl,
+		rules: make(map[string]*MarketRules),
+	}
+}
+
+// SetRules overrides the default rules of a market.
+func (ov *OrderValidator) SetRules(rules *MarketRules) {
+	ov.mu.Lock()
+	ov.rules[strings.ToUpper(rules.Market)] = rules
+	ov.mu.Unlock()
+}
+
+// Invalidate drops the cached markets and balances.
+func (ov *OrderValidator) Invalidate() {
+	ov.mu.Lock()
+	ov.fetchedAt = time.Time{}
+	ov.mu.Unlock()
+}
+
+// refresh fetches markets and balances when the cache expired. The requests
+// are made without holding ov.mu; when another refresh completed meanwhile,
+// its result is kept.
+func (ov *OrderValidator) refresh() error {
+	ov.mu.Lock()
+	fetchedAt := ov.fetchedAt
+	ov.mu.Unlock()
+	if !fetchedAt.IsZero() && time.Since(fetchedAt) < ov.ttl {
+		return nil
+	}
+
+	pub := &PublicService{client: ov.ps.client}
+	mr, err := pub.GetMarkets()
+	if err != nil {
+		return err
+	}
+	br, err := ov.ps.GetBalance()
+	if err != nil {
+		return err
+	}
+
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+	if !ov.fetchedAt.Equal(fetchedAt) {
+		return nil
+	}
+	ov.markets = make(map[string]bool)
+	for _, m := range mr.Data {
+		ov.markets[strings.ToUpper(m)] = true
+	}
+	ov.balances = make(map[string]*Balance)
+	for _, b := range br.Data {
+		ov.balances[strings.ToUpper(b.Wallet)] = b
+	}
+	ov.locked = make(map[string]float64)
+	ov.reserved = make(map[*MarketOrderRequest][]reservation)
+	ov.fetchedAt = time.Now()
+
+	return nil
+}
+
+// Validate checks side, market, price tick, minimum amount and available
+// funds of mor. Funds of orders validated since the last refresh are locked
+// until the next one, unless they are released. The returned error is a
+// *ValidationError unless markets or balances could not be fetched.
+func (ov *OrderValidator) Validate(mor *MarketOrderRequest) error {
+	if mor.Type != "buy" && mor.Type != "sell" {
+		return &ValidationError{"type", fmt.Sprintf("must be buy or sell, got %q", mor.Type)}
+	}
+
+	if err := ov.refresh(); err != nil {
+		return err
+	}
+
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+
+	market := strings.ToUpper(mor.Market)
+	if !ov.markets[market] {
+		return &ValidationError{"market", fmt.Sprintf("%s is not available", mor.Market)}
+	}
+
+	rules, ok := ov.rules[market]
+	if !ok {
+		var err error
+		if rules, err = DefaultMarketRules(market); err != nil {
+			return &ValidationError{"market", fmt.Sprintf("%s has an unknown quote currency", market)}
+		}
+	}
+
+	if !fiatCurrencies[rules.Quote] {
+		return &ValidationError{"price", fmt.Sprintf("%s is quoted in %s and needs fractional prices", market, rules.Quote)}
+	}
+	if mor.Price <= 0 {
+		return &ValidationError{"price", "must be positive"}
+	}
+	if rules.PriceTick > 1 && mor.Price%rules.PriceTick != 0 {
+		return &ValidationError{"price", fmt.Sprintf("must be a multiple of %d", rules.PriceTick)}
+	}
+	if mor.Amount <= 0 || mor.Amount < rules.MinAmount {
+		return &ValidationError{"amount", fmt.Sprintf("must be at least %v %s", rules.MinAmount, rules.Base)}
+	}
+
+	wallet, cost := rules.Base, mor.Amount
+	if mor.Type == "buy" {
+		wallet, cost = rules.Quote, mor.Amount*float64(mor.Price)
+	}
+	available := -ov.locked[wallet]
+	if b, ok := ov.balances[wallet]; ok {
+		available += b.Available
+	}
+	if cost > available {
+		return &ValidationError{"amount", fmt.Sprintf("needs %v %s, only %v available", cost, wallet, available)}
+	}
+	ov.locked[wallet] += cost
+	ov.reserved[mor] = append(ov.reserved[mor], reservation{wallet, cost})
+
+	return nil
+}
+
+// Release unlocks the funds of a validated order that was not created. The
+// funds of orders validated before the last refresh are already unlocked.
+func (ov *OrderValidator) Release(mor *MarketOrderRequest) {
+	ov.mu.Lock()
+	defer ov.mu.Unlock()
+
+	rs := ov.reserved[mor]
+	if len(rs) == 0 {
+		return
+	}
+	r := rs[len(rs)-1]
+	if len(rs) == 1 {
+		delete(ov.reserved, mor)
+	} else {
+		ov.reserved[mor] = rs[:len(rs)-1]
+	}
+	ov.locked[r.wallet] -= r.amount
+}
@@ -0,0 +1,179 @@
+package cryptomkt
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_OrderValidator(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/market":
+			w.Write(getMarketsResponse)
+		case "/v1/balance":
+			w.Write(getBalanceResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+
+	ov := NewOrderValidator(ps, time.Minute)
+	cases := []struct {
+		mor   *MarketOrderRequest
+		field string
+	}{
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "hold"}, "type"},
+		{&MarketOrderRequest{Market: "ETHBRL", Amount: 0.3, Price: 10000, Type: "buy"}, "market"},
+		{&MarketOrderRequest{Market: "XLMBTC", Amount: 10, Price: 1, Type: "buy"}, "price"},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 0.0001, Price: 10000, Type: "buy"}, "amount"},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 11, Price: 10000, Type: "sell"}, "amount"},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 10, Price: 10000, Type: "buy"}, ""},
+		{&MarketOrderRequest{Market: "ETHCLP", Amount: 10, Price: 10000, Type: "buy"}, "amount"},
+		{&MarketOrderRequest{Market: "ethclp", Amount: 10, Price: 10000, Type: "sell"}, ""},
+	}
+
+	for i, c := range cases {
+		err := ov.Validate(c.mor)
+		if c.field == "" {
+			if err != nil {
+				t.Errorf("Unexpected error in case %d: %v", i, err)
+			}
+			continue
+		}
+
+		var valErr *ValidationError
+		if !errors.As(err, &valErr) || valErr.Field != c.field {
+			t.Errorf("Expected validation error on %s in case %d, got %v", c.field, i, err)
+		}
+	}
+}
+
+func Test_OrderValidatorRefresh(t *testing.T) {
+	block := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/market":
+			w.Write(getMarketsResponse)
+		case "/v1/balance":
+			<-block
+			w.Write(getBalanceResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+	ps := &PrivateService{
+		client: &httpClient{
+			client:  httpCli,
+			creds:   NewStaticProvider("some-key", "some-secret"),
+			private: true,
+		},
+		Private: true,
+	}
+	ov := NewOrderValidator(ps, time.Minute)
+
+	done := make(chan error, 1)
+	old := &MarketOrderRequest{Market: "ETHCLP", Amount: 10, Price: 10000, Type: "buy"}
+	go func() { done <- ov.Validate(old) }()
+	// The validator is not locked while the balance is fetched.
+	time.Sleep(50 * time.Millisecond)
+	ov.SetRules(&MarketRules{Market: "ETHCLP", Base: "ETH", Quote: "CLP", PriceTick: 1})
+	close(block)
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	ov.Invalidate()
+	if err := ov.Validate(&MarketOrderRequest{Market: "ETHCLP", Amount: 10, Price: 10000, Type: "buy"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// old was validated against the previous balance, its funds are no
+	// longer locked.
+	ov.Release(old)
+
+	var valErr *ValidationError
+	err := ov.Validate(&MarketOrderRequest{Market: "ETHCLP", Amount: 10, Price: 10000, Type: "buy"})
+	if !errors.As(err, &valErr) || valErr.Field != "amount" {
+		t.Errorf("Expected insufficient funds, got %v", err)
+	}
+}
+
+func Test_CreateOrderValidation(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/market":
+			w.Write(getMarketsResponse)
+		case "/v1/balance":
+			w.Write(getBalanceResponse)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	c := NewClient("some-key", "some-secret", WithOrderValidation(time.Minute))
+	c.PrivateService.client.client = httpCli
+
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 20, Price: 10000, Type: "buy"}
+	_, err := c.CreateOrder(mor)
+
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Errorf("Expected *ValidationError, got %v", err)
+	}
+}
+
+func Test_CreateOrderValidationFetchFails(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/market":
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	})
+	httpCli, teardown := testingHTTPClient(h)
+	defer teardown()
+
+	rm := NewRiskManager(RiskLimits{MaxDailyVolume: map[string]float64{"ETHCLP": 10000}})
+	c := NewClient("some-key", "some-secret", WithOrderValidation(time.Minute), WithRiskManager(rm))
+	c.PrivateService.client.client = httpCli
+
+	mor := &MarketOrderRequest{Market: "ETHCLP", Amount: 0.3, Price: 10000, Type: "buy", ClientOrderID: "my-order-1"}
+	_, err := c.CreateOrderIdempotent(mor)
+	var tErr *TransportError
+	if !errors.As(err, &tErr) {
+		t.Errorf("Expected *TransportError, got %v", err)
+	}
+	if isAmbiguous(err) {
+		t.Errorf("Expected an order rejected before sending, got %v", err)
+	}
+	if v := rm.dailyVolume("ETHCLP"); v != 0 {
+		t.Errorf("Expected no volume recorded, got %v", v)
+	}
+	if n := len(rm.created["ETHCLP"]); n != 0 {
+		t.Errorf("Expected no open order recorded, got %d", n)
+	}
+}
+
+var getMarketsResponse = []byte(`
+	{
+		"status": "success",
+		"data": ["ETHCLP", "ETHARS", "XLMBTC"]
+	}
+`)